@@ -0,0 +1,134 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+)
+
+// Address is an Elastic IP address
+type Address struct {
+	PublicIP     string
+	AllocationID string
+	// InstanceID is the instance the address is associated with, if any
+	InstanceID string
+	// AssociationID is set when the address is associated with an instance
+	// or a network interface, such as that of a NAT gateway
+	AssociationID string
+	Region        string
+}
+
+// Unattached reports whether the address isn't associated with anything, so
+// it can be released without affecting any resource
+func (a Address) Unattached() bool {
+	return a.AssociationID == "" && a.InstanceID == ""
+}
+
+// Addresses returns the Elastic IP addresses in a region. DescribeAddresses
+// returns every address in one call, so a region needs a single request.
+func Addresses(ctx context.Context, creds Credentials, region string) ([]Address, error) {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return nil, err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	result, err := svc.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]Address, 0, len(result.Addresses))
+	for _, a := range result.Addresses {
+		addresses = append(addresses, Address{
+			PublicIP:      aws.ToString(a.PublicIp),
+			AllocationID:  aws.ToString(a.AllocationId),
+			InstanceID:    aws.ToString(a.InstanceId),
+			AssociationID: aws.ToString(a.AssociationId),
+			Region:        region,
+		})
+	}
+	return addresses, nil
+}
+
+// ReleaseAddress releases an Elastic IP address. If dryRun is set, only the
+// permission check is performed.
+func ReleaseAddress(ctx context.Context, creds Credentials, region string, allocationID string, dryRun bool) error {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	_, err = svc.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{
+		AllocationId: aws.String(allocationID),
+		DryRun:       aws.Bool(dryRun),
+	})
+	// If the error code is `DryRunOperation` it means we have the necessary
+	// permissions to release the address
+	if dryRun && err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == DryRunOperation {
+			return nil
+		}
+	}
+	return err
+}
+
+// IdleInstance is a stopped instance along with the resources it still
+// incurs costs for
+type IdleInstance struct {
+	Instance  Instance
+	Addresses []Address
+}
+
+// WriteIdle writes the stopped instances and the resources they still incur
+// costs for to w, followed by the Elastic IP addresses that aren't associated
+// with any instance
+func WriteIdle(w io.Writer, idle []IdleInstance, unattached []Address) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tID\tREGION\tVOLUMES\tVOLUME GB\tELASTIC IPS")
+	for _, i := range idle {
+		ips := make([]string, 0, len(i.Addresses))
+		for _, a := range i.Addresses {
+			ips = append(ips, a.PublicIP)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n",
+			i.Instance.Name, i.Instance.ID, i.Instance.Region,
+			i.Instance.VolumeCount, i.Instance.TotalVolumeSizeGB, strings.Join(ips, ","))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(unattached) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "\nElastic IPs not associated with any instance:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PUBLIC IP\tALLOCATION ID\tREGION")
+	for _, a := range unattached {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a.PublicIP, a.AllocationID, a.Region)
+	}
+	return tw.Flush()
+}
+
+// IdleSummary renders a one-line rollup of the idle resources, e.g.
+// "3 stopped instances with 120 GB of volumes and 2 Elastic IPs; 1 unattached Elastic IPs"
+func IdleSummary(idle []IdleInstance, unattached []Address) string {
+	size, ips := 0, 0
+	for _, i := range idle {
+		size += i.Instance.TotalVolumeSizeGB
+		ips += len(i.Addresses)
+	}
+	return fmt.Sprintf("%d stopped instances with %d GB of volumes and %d Elastic IPs; %d unattached Elastic IPs",
+		len(idle), size, ips, len(unattached))
+}
@@ -0,0 +1,157 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	// maxRetryAttempts is the maximum number of attempts made for an API call
+	// before giving up, including the initial attempt
+	maxRetryAttempts = 10
+	// maxRetryBackoff is the upper bound of the exponential backoff between attempts
+	maxRetryBackoff = 30 * time.Second
+)
+
+// Credentials holds the options used to resolve the AWS credentials for a command
+type Credentials struct {
+	// Profile is the named profile to load from the shared config files
+	Profile string
+	// RoleARN is the ARN of a role to assume on top of the resolved credentials
+	RoleARN string
+	// ExternalID is the optional external ID required by the role's trust policy
+	ExternalID string
+}
+
+// Validate checks that the credential options are well formed
+func (c Credentials) Validate() error {
+	if c.RoleARN == "" {
+		if c.ExternalID != "" {
+			return errors.New("an external ID can only be used together with a role ARN")
+		}
+		return nil
+	}
+	parsed, err := arn.Parse(c.RoleARN)
+	if err != nil {
+		return fmt.Errorf("invalid role ARN %q: %w", c.RoleARN, err)
+	}
+	if parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return fmt.Errorf("invalid role ARN %q: not an IAM role", c.RoleARN)
+	}
+	return nil
+}
+
+// loadConfig loads the AWS configuration for the given region. Config sources
+// passed to LoadDefaultConfig take priority over the standard environment and
+// shared configuration values. If a role ARN is set, the resolved credentials
+// are used to assume that role.
+//
+// Throttled calls (e.g. RequestLimitExceeded when querying many regions at
+// once) are retried with exponential backoff. The client-side retry quota is
+// disabled so that a burst of throttling doesn't exhaust it and fail fast.
+func loadConfig(ctx context.Context, region string, creds Credentials) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(region),
+		config.WithSharedConfigProfile(creds.Profile),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = maxRetryAttempts
+				o.MaxBackoff = maxRetryBackoff
+				o.RateLimiter = ratelimit.None
+			})
+		}),
+	)
+	if err != nil {
+		return cfg, err
+	}
+
+	if creds.RoleARN != "" {
+		if err := creds.Validate(); err != nil {
+			return cfg, err
+		}
+		Logger.Debug("assuming role", "region", region, "role", creds.RoleARN)
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), creds.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if creds.ExternalID != "" {
+				o.ExternalID = aws.String(creds.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cfg, nil
+}
+
+// DefaultRegion returns the region configured for the credentials, resolved
+// from the AWS_REGION environment variable or the profile's region, falling
+// back to AWS_DEFAULT_REGION as the AWS CLI does. It returns an empty string
+// if no region is configured.
+func DefaultRegion(ctx context.Context, creds Credentials) (string, error) {
+	cfg, err := loadConfig(ctx, "", creds)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Region != "" {
+		return cfg.Region, nil
+	}
+	return os.Getenv("AWS_DEFAULT_REGION"), nil
+}
+
+// AccountID returns the ID of the AWS account the credentials belong to
+func AccountID(ctx context.Context, creds Credentials) (string, error) {
+	cfg, err := loadConfig(ctx, "", creds)
+	if err != nil {
+		return "", err
+	}
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(identity.Account), nil
+}
+
+// CallerARN returns the ARN of the identity the credentials belong to
+func CallerARN(ctx context.Context, creds Credentials) (string, error) {
+	cfg, err := loadConfig(ctx, "", creds)
+	if err != nil {
+		return "", err
+	}
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(identity.Arn), nil
+}
+
+// CredentialsEnv resolves the credentials for a region and returns them as the
+// AWS_* environment variables understood by other AWS tools, such as the AWS CLI
+func CredentialsEnv(ctx context.Context, creds Credentials, region string) ([]string, error) {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	env := []string{
+		"AWS_ACCESS_KEY_ID=" + resolved.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + resolved.SecretAccessKey,
+		"AWS_REGION=" + region,
+	}
+	if resolved.SessionToken != "" {
+		env = append(env, "AWS_SESSION_TOKEN="+resolved.SessionToken)
+	}
+	return env, nil
+}
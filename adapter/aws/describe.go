@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// DescribeInstance returns the full description of a single instance in a region
+func DescribeInstance(ctx context.Context, creds Credentials, region string, instanceID string) (types.Instance, error) {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return types.Instance{}, err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	result, err := svc.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return types.Instance{}, err
+	}
+	for _, res := range result.Reservations {
+		for _, inst := range res.Instances {
+			return inst, nil
+		}
+	}
+	return types.Instance{}, fmt.Errorf("instance %s not found in region %s", instanceID, region)
+}
+
+// WriteDetails writes the description of an instance in a region to w as a
+// vertical list of fields and their values
+func WriteDetails(w io.Writer, region string, inst types.Instance) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(key string, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", key, value)
+		}
+	}
+
+	row("ID", aws.ToString(inst.InstanceId))
+	row("Region", region)
+	row("Type", string(inst.InstanceType))
+	if inst.State != nil {
+		row("State", string(inst.State.Name))
+	}
+	if inst.StateReason != nil {
+		row("State Reason", aws.ToString(inst.StateReason.Message))
+	}
+	if inst.LaunchTime != nil {
+		row("Launch Time", inst.LaunchTime.Format(time.RFC3339))
+	}
+	row("Lifecycle", string(inst.InstanceLifecycle))
+	row("Image", aws.ToString(inst.ImageId))
+	row("Platform", aws.ToString(inst.PlatformDetails))
+	row("Architecture", string(inst.Architecture))
+	row("Key Name", aws.ToString(inst.KeyName))
+	if inst.IamInstanceProfile != nil {
+		row("IAM Profile", aws.ToString(inst.IamInstanceProfile.Arn))
+	}
+
+	// Networking
+	if inst.Placement != nil {
+		row("Availability Zone", aws.ToString(inst.Placement.AvailabilityZone))
+	}
+	row("VPC", aws.ToString(inst.VpcId))
+	row("Subnet", aws.ToString(inst.SubnetId))
+	row("Private IP", aws.ToString(inst.PrivateIpAddress))
+	row("Private DNS", aws.ToString(inst.PrivateDnsName))
+	row("Public IP", aws.ToString(inst.PublicIpAddress))
+	row("Public DNS", aws.ToString(inst.PublicDnsName))
+	for _, group := range inst.SecurityGroups {
+		row("Security Group", fmt.Sprintf("%s (%s)", aws.ToString(group.GroupName), aws.ToString(group.GroupId)))
+	}
+	for _, eni := range inst.NetworkInterfaces {
+		row("Network Interface", fmt.Sprintf("%s (%s)", aws.ToString(eni.NetworkInterfaceId), aws.ToString(eni.PrivateIpAddress)))
+	}
+
+	// Block devices
+	row("Root Device", aws.ToString(inst.RootDeviceName))
+	for _, mapping := range inst.BlockDeviceMappings {
+		if mapping.Ebs != nil {
+			row("Block Device", fmt.Sprintf("%s %s", aws.ToString(mapping.DeviceName), aws.ToString(mapping.Ebs.VolumeId)))
+		}
+	}
+
+	// Tags, in key order
+	tags := make([]string, 0, len(inst.Tags))
+	for _, tag := range inst.Tags {
+		tags = append(tags, aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+	}
+	sort.Strings(tags)
+	row("Tags", strings.Join(tags, "\n\t"))
+
+	return tw.Flush()
+}
@@ -0,0 +1,39 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InstanceOpError records an operation on instances that failed, along with
+// the instances and region it was applied to
+type InstanceOpError struct {
+	// InstanceID is the instance the operation failed on. For operations on
+	// a batch of instances, it holds their comma-separated IDs.
+	InstanceID string
+	Region     string
+	Action     string
+	Err        error
+}
+
+func (e *InstanceOpError) Error() string {
+	return fmt.Sprintf("%s %s in region %s: %v", e.Action, e.InstanceID, e.Region, e.Err)
+}
+
+func (e *InstanceOpError) Unwrap() error {
+	return e.Err
+}
+
+// newInstanceOpError wraps err in an InstanceOpError for the instances, or
+// returns nil if err is nil
+func newInstanceOpError(action string, region string, instanceIDs []string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &InstanceOpError{
+		InstanceID: strings.Join(instanceIDs, ","),
+		Region:     region,
+		Action:     action,
+		Err:        err,
+	}
+}
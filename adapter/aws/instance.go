@@ -4,11 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"slices"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/smithy-go"
@@ -32,155 +33,332 @@ type Instance struct {
 	Name             string
 	ID               string
 	Status           types.InstanceStateName
+	HealthStatus     string
 	Type             types.InstanceType
 	Lifecycle        string
 	Environment      string
 	IP               string
+	PublicIP         string
 	SpotInstanceType types.SpotInstanceType
+	SpotRequestID    string `table:"wide"`
+	SpotMaxPrice     string `json:",omitempty" table:"-"`
 	Region           string
 	AZ               string
 	Hibernation      bool
+	LaunchTime       time.Time
+	// Platform is either windows or linux
+	Platform string
+	// The following fields are only rendered in wide tables
+	VpcID             string `table:"wide"`
+	SubnetID          string `table:"wide"`
+	KeyName           string `table:"wide"`
+	AMI               string `table:"wide"`
+	PlatformDetails   string `table:"wide"`
+	SecurityGroups    string `table:"wide"`
+	IAMProfile        string `table:"wide"`
+	VolumeCount       int    `table:"wide"`
+	TotalVolumeSizeGB int    `table:"wide"`
+	// MonthlyCost is the estimated monthly on-demand cost, only set when requested
+	MonthlyCost string `json:",omitempty" table:",omitempty"`
+	// Tags holds every tag on the instance, including Name and Environment
+	Tags map[string]string `table:"-"`
 }
 
-// GetDeployedInstances retrieves the status of all deployed instances in a given region
-func GetDeployedInstances(c chan RegionSummary, region string, tags map[string]string, action string, instanceIDs []string) {
-	ctx := context.TODO()
+// GetDeployedInstances retrieves the status of the deployed instances in a given region
+// that match the filter.
+func GetDeployedInstances(ctx context.Context, c chan RegionSummary, creds Credentials, region string, filter Filter) {
 	var rSummary RegionSummary
 	rSummary.Region = region
 
-	// Config sources can be passed to LoadDefaultConfig, these sources can implement
-	// one or more provider interfaces. These sources take priority over the standard
-	// environment and shared configuration values.
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-	)
+	cfg, err := loadConfig(ctx, region, creds)
 	if err != nil {
-		log.Fatal(err)
+		rSummary.Err = err
+		c <- rSummary
+		return
 	}
 
 	svc := ec2.NewFromConfig(cfg)
+	rSummary.Instances, rSummary.Err = describeInstances(ctx, svc, region, filter)
+	if rSummary.Err == nil {
+		Logger.Debug("described instances", "region", region, "count", len(rSummary.Instances))
+	}
 
-	// Filter by state type
-	var stateFilter types.Filter
-	switch action {
-	case InstanceStop:
-		stateFilter = types.Filter{
-			Name: aws.String("instance-state-name"),
-			Values: []string{
-				string(types.InstanceStateNameRunning),
-			},
-		}
-	case InstanceStart:
-		stateFilter = types.Filter{
-			Name: aws.String("instance-state-name"),
-			Values: []string{
-				string(types.InstanceStateNameStopped),
-			},
-		}
-	default:
-		stateFilter = types.Filter{
-			Name: aws.String("instance-state-name"),
-			Values: []string{
-				string(types.InstanceStateNamePending),
-				string(types.InstanceStateNameRunning),
-				string(types.InstanceStateNameShuttingDown),
-				string(types.InstanceStateNameStopping),
-				string(types.InstanceStateNameStopped),
-			},
-		}
+	c <- rSummary
+}
+
+// describeAPI is the part of the EC2 API used to describe the instances of a
+// region
+type describeAPI interface {
+	ec2.DescribeInstancesAPIClient
+	ec2.DescribeInstanceStatusAPIClient
+	ec2.DescribeVolumesAPIClient
+	ec2.DescribeSpotInstanceRequestsAPIClient
+}
+
+// describeInstances describes the instances in a region that match the filter,
+// along with their spot requests, volumes and status checks
+func describeInstances(ctx context.Context, svc describeAPI, region string, filter Filter) ([]Instance, error) {
+	// Filter by state type, defaulting to the states the action applies to
+	states := filter.States
+	if len(states) == 0 {
+		states = ActionStates(filter.Action)
+	}
+	stateValues := make([]string, 0, len(states))
+	for _, state := range states {
+		stateValues = append(stateValues, string(state))
+	}
+	stateFilter := types.Filter{
+		Name:   aws.String("instance-state-name"),
+		Values: stateValues,
 	}
 
 	filters := []types.Filter{stateFilter}
 
-	// Filter by tag type
-	for tagKey, tagVal := range tags {
+	// Filter by tag type, matching any of the values given for a key
+	for tagKey, tagVals := range filter.Tags {
 		newTagFilter := types.Filter{
-			Name: aws.String("tag:" + tagKey),
-			Values: []string{
-				tagVal,
-			},
+			Name:   aws.String("tag:" + tagKey),
+			Values: tagVals,
 		}
 		filters = append(filters, newTagFilter)
 	}
 
+	// Filter by instance type
+	if len(filter.InstanceTypes) != 0 {
+		typeFilter := types.Filter{
+			Name:   aws.String("instance-type"),
+			Values: filter.InstanceTypes,
+		}
+		filters = append(filters, typeFilter)
+	}
+
+	// Filter by availability zone
+	if len(filter.Zones) != 0 {
+		zoneFilter := types.Filter{
+			Name:   aws.String("availability-zone"),
+			Values: filter.Zones,
+		}
+		filters = append(filters, zoneFilter)
+	}
+
+	// Filter by network
+	if len(filter.VpcIDs) != 0 {
+		vpcFilter := types.Filter{
+			Name:   aws.String("vpc-id"),
+			Values: filter.VpcIDs,
+		}
+		filters = append(filters, vpcFilter)
+	}
+	if len(filter.SubnetIDs) != 0 {
+		subnetFilter := types.Filter{
+			Name:   aws.String("subnet-id"),
+			Values: filter.SubnetIDs,
+		}
+		filters = append(filters, subnetFilter)
+	}
+
+	if len(filter.AMIIDs) != 0 {
+		amiFilter := types.Filter{
+			Name:   aws.String("image-id"),
+			Values: filter.AMIIDs,
+		}
+		filters = append(filters, amiFilter)
+	}
+	// EC2 tags the instances it launches from a launch template with the
+	// template's ID
+	if len(filter.LaunchTemplateIDs) != 0 {
+		launchTemplateFilter := types.Filter{
+			Name:   aws.String("tag:aws:ec2launchtemplate:id"),
+			Values: filter.LaunchTemplateIDs,
+		}
+		filters = append(filters, launchTemplateFilter)
+	}
+
+	// Filter by the presence of tag keys. EC2 matches any of the keys, so
+	// instances missing some of them are dropped once described.
+	if len(filter.TagKeys) != 0 {
+		tagKeyFilter := types.Filter{
+			Name:   aws.String("tag-key"),
+			Values: filter.TagKeys,
+		}
+		filters = append(filters, tagKeyFilter)
+	}
+
+	// Filter by lifecycle. EC2 leaves the lifecycle of on-demand instances
+	// unset, so they can only be matched once described.
+	if filter.Lifecycle != "" && filter.Lifecycle != string(types.InstanceLifecycleOnDemand) {
+		lifecycleFilter := types.Filter{
+			Name:   aws.String("instance-lifecycle"),
+			Values: []string{filter.Lifecycle},
+		}
+		filters = append(filters, lifecycleFilter)
+	}
+
 	// Filter by instanceIDs
-	if len(instanceIDs) != 0 {
+	if len(filter.InstanceIDs) != 0 {
 		idFilter := types.Filter{
 			Name:   aws.String("instance-id"),
-			Values: instanceIDs,
+			Values: filter.InstanceIDs,
 		}
 		filters = append(filters, idFilter)
 	}
 
+	filterNames := make([]string, 0, len(filters))
+	for _, f := range filters {
+		filterNames = append(filterNames, fmt.Sprintf("%s=%v", aws.ToString(f.Name), f.Values))
+	}
+	Logger.Debug("describing instances", "region", region, "filters", filterNames)
+
 	input := &ec2.DescribeInstancesInput{
 		Filters: filters,
 	}
 
-	result, err := svc.DescribeInstances(ctx, input)
-	if err != nil {
-		fmt.Println(err.Error())
-		c <- rSummary
-		return
+	var reservations []types.Reservation
+	instancePaginator := ec2.NewDescribeInstancesPaginator(svc, input)
+	for instancePaginator.HasMorePages() {
+		page, err := instancePaginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, page.Reservations...)
 	}
 
-	inputStatus := &ec2.DescribeInstanceStatusInput{
-		Filters: []types.Filter{
-			stateFilter,
-		},
-		IncludeAllInstances: aws.Bool(true),
+	// Look up the spot requests the instances were launched
+	// by, rather than every spot request in the region
+	var spotRequestIDs []string
+	for _, res := range reservations {
+		for _, inst := range res.Instances {
+			if inst.SpotInstanceRequestId != nil {
+				spotRequestIDs = append(spotRequestIDs, *inst.SpotInstanceRequestId)
+			}
+		}
+	}
+	spotRequests, err := getSpotRequests(ctx, svc, spotRequestIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	resultStatus, err := svc.DescribeInstanceStatus(ctx, inputStatus)
+	// Look up the sizes of the attached volumes, which aren't part of the
+	// instance description, in batches for the whole region
+	var volumeIDs []string
+	for _, res := range reservations {
+		for _, inst := range res.Instances {
+			for _, mapping := range inst.BlockDeviceMappings {
+				if mapping.Ebs != nil && mapping.Ebs.VolumeId != nil {
+					volumeIDs = append(volumeIDs, *mapping.Ebs.VolumeId)
+				}
+			}
+		}
+	}
+	volumeSizes, err := getVolumeSizes(ctx, svc, volumeIDs)
 	if err != nil {
-		fmt.Println(err.Error())
-		c <- rSummary
-		return
+		return nil, err
 	}
 
-	spotDetail, err := svc.DescribeSpotInstanceRequests(ctx, &ec2.DescribeSpotInstanceRequestsInput{})
+	// Look up the status checks of the running instances, which are also
+	// described separately
+	var runningIDs []string
+	for _, res := range reservations {
+		for _, inst := range res.Instances {
+			if inst.State != nil && inst.State.Name == types.InstanceStateNameRunning {
+				runningIDs = append(runningIDs, aws.ToString(inst.InstanceId))
+			}
+		}
+	}
+	healthStatuses, err := getHealthStatuses(ctx, svc, runningIDs)
 	if err != nil {
-		fmt.Println(err.Error())
-		c <- rSummary
-		return
+		return nil, err
 	}
 
 	var instances []Instance
 	var instance Instance
 
-	for _, res := range result.Reservations {
+	for _, res := range reservations {
 		for _, inst := range res.Instances {
-			instance.ID = *inst.InstanceId
-			instance.Status = inst.State.Name
+			// Guard against partial responses, so a single malformed
+			// instance doesn't abort the whole region
+			instance.ID = aws.ToString(inst.InstanceId)
+			instance.Status = ""
+			if inst.State != nil {
+				instance.Status = inst.State.Name
+			}
 			instance.Type = inst.InstanceType
-			instance.IP = *inst.PrivateIpAddress
-			instance.Hibernation = *inst.HibernationOptions.Configured
+			instance.IP = aws.ToString(inst.PrivateIpAddress)
+			instance.PublicIP = aws.ToString(inst.PublicIpAddress)
+			instance.Hibernation = inst.HibernationOptions != nil && aws.ToBool(inst.HibernationOptions.Configured)
 			instance.Region = region
-			instance.AZ = getInstanceAZ(resultStatus.InstanceStatuses, inst.InstanceId)
+			instance.LaunchTime = aws.ToTime(inst.LaunchTime)
+			instance.HealthStatus = healthStatuses[instance.ID]
+			instance.AZ = ""
+			if inst.Placement != nil {
+				instance.AZ = aws.ToString(inst.Placement.AvailabilityZone)
+			}
+			instance.VpcID = aws.ToString(inst.VpcId)
+			instance.SubnetID = aws.ToString(inst.SubnetId)
+			instance.KeyName = aws.ToString(inst.KeyName)
+			instance.AMI = aws.ToString(inst.ImageId)
+			// EC2 only sets the platform of Windows instances
+			instance.Platform = PlatformLinux
+			if inst.Platform == types.PlatformValuesWindows {
+				instance.Platform = PlatformWindows
+			}
+			instance.PlatformDetails = aws.ToString(inst.PlatformDetails)
+			groupNames := make([]string, 0, len(inst.SecurityGroups))
+			for _, group := range inst.SecurityGroups {
+				groupNames = append(groupNames, aws.ToString(group.GroupName))
+			}
+			instance.SecurityGroups = strings.Join(groupNames, ",")
+			instance.IAMProfile = ""
+			if inst.IamInstanceProfile != nil {
+				instance.IAMProfile = aws.ToString(inst.IamInstanceProfile.Arn)
+			}
+			instance.VolumeCount = 0
+			instance.TotalVolumeSizeGB = 0
+			for _, mapping := range inst.BlockDeviceMappings {
+				if mapping.Ebs != nil && mapping.Ebs.VolumeId != nil {
+					instance.VolumeCount++
+					instance.TotalVolumeSizeGB += volumeSizes[*mapping.Ebs.VolumeId]
+				}
+			}
 			instance.SpotInstanceType = ""
+			instance.SpotRequestID = ""
+			instance.SpotMaxPrice = ""
 			if inst.InstanceLifecycle == "" {
 				instance.Lifecycle = string(types.InstanceLifecycleOnDemand)
 			} else {
 				instance.Lifecycle = string(inst.InstanceLifecycle)
 				if inst.InstanceLifecycle == types.InstanceLifecycleTypeSpot {
-					instance.SpotInstanceType = getSpotRequestType(spotDetail.SpotInstanceRequests, inst.SpotInstanceRequestId)
+					request := spotRequests[aws.ToString(inst.SpotInstanceRequestId)]
+					instance.SpotInstanceType = request.Type
+					instance.SpotRequestID = aws.ToString(inst.SpotInstanceRequestId)
+					instance.SpotMaxPrice = aws.ToString(request.SpotPrice)
 				}
 			}
 
 			if inst.StateReason != nil {
-				if *inst.StateReason.Code == "Client.UserInitiatedHibernate" && inst.State.Name == types.InstanceStateNameStopped {
+				if aws.ToString(inst.StateReason.Code) == "Client.UserInitiatedHibernate" && instance.Status == types.InstanceStateNameStopped {
 					instance.Status = "hibernated"
 				}
 			}
 
 			instance.Name = ""
 			instance.Environment = ""
+			instance.Tags = make(map[string]string, len(inst.Tags))
 			for _, tag := range inst.Tags {
-				if *tag.Key == "Name" {
-					instance.Name = *tag.Value
-				} else if *tag.Key == "Environment" {
-					instance.Environment = *tag.Value
+				key, value := aws.ToString(tag.Key), aws.ToString(tag.Value)
+				instance.Tags[key] = value
+				if key == "Name" {
+					instance.Name = value
+				} else if key == "Environment" {
+					instance.Environment = value
 				}
 			}
+			if !hasTagKeys(instance.Tags, filter.TagKeys) {
+				continue
+			}
+			if filter.Lifecycle != "" && instance.Lifecycle != filter.Lifecycle {
+				continue
+			}
 			instances = append(instances, instance)
 		}
 	}
@@ -194,26 +372,72 @@ func GetDeployedInstances(c chan RegionSummary, region string, tags map[string]s
 		return instances[i].Name < instances[j].Name
 	})
 
-	rSummary.Instances = instances
+	return instances, nil
+}
 
-	c <- rSummary
+// hasTagKeys reports whether tags has every one of the keys
+func hasTagKeys(tags map[string]string, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := tags[key]; !ok {
+			return false
+		}
+	}
+	return true
 }
 
-// StartStopInstance starts or stops an AWS Instance
-func StartStopInstance(region string, action string, instanceIDs []string) ([]types.InstanceStateChange, error) {
-	ctx := context.TODO()
-	// Config sources can be passed to LoadDefaultConfig, these sources can implement
-	// one or more provider interfaces. These sources take priority over the standard
-	// environment and shared configuration values.
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-	)
+// StartStopInstance starts or stops AWS instances. If dryRun is set, only the
+// permission check is performed and the returned state changes describe the
+// transitions that would have been requested.
+//
+// EC2 rejects the whole batch when any instance is in a state the action
+// can't be applied to, so in that case the instances are retried one at a
+// time. The instances that still fail are returned with their errors, while
+// the state changes of the others are returned as usual. Errors are returned
+// as *InstanceOpError values.
+func StartStopInstance(ctx context.Context, creds Credentials, region string, action string, instanceIDs []string, dryRun bool) ([]types.InstanceStateChange, map[string]error, error) {
+	cfg, err := loadConfig(ctx, region, creds)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, newInstanceOpError(action, region, instanceIDs, err)
 	}
 	// Create new EC2 client
 	svc := ec2.NewFromConfig(cfg)
 
+	Logger.Debug("changing instance state", "region", region, "action", action, "instances", instanceIDs, "dryRun", dryRun)
+	changes, err := changeInstanceState(ctx, svc, action, instanceIDs, dryRun)
+	if err == nil || len(instanceIDs) < 2 || !isIncorrectStateError(err) {
+		return changes, nil, newInstanceOpError(action, region, instanceIDs, err)
+	}
+
+	Logger.Debug("retrying instances individually", "region", region, "action", action, "error", err)
+	changes = nil
+	failed := make(map[string]error)
+	for _, id := range instanceIDs {
+		change, err := changeInstanceState(ctx, svc, action, []string{id}, dryRun)
+		if err != nil {
+			failed[id] = newInstanceOpError(action, region, []string{id}, err)
+			continue
+		}
+		changes = append(changes, change...)
+	}
+	return changes, failed, nil
+}
+
+// isIncorrectStateError reports whether err is EC2 rejecting an action because
+// an instance isn't in a state, or configuration, the action can be applied to
+func isIncorrectStateError(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	switch ae.ErrorCode() {
+	case "IncorrectInstanceState", "IncorrectState", "UnsupportedHibernationConfiguration":
+		return true
+	}
+	return false
+}
+
+// changeInstanceState makes the API calls that start or stop the instances
+func changeInstanceState(ctx context.Context, svc *ec2.Client, action string, instanceIDs []string, dryRun bool) ([]types.InstanceStateChange, error) {
 	switch action {
 	case InstanceStart:
 		// We set DryRun to true to check to see if the instance exists, and we have the
@@ -229,6 +453,9 @@ func StartStopInstance(region string, action string, instanceIDs []string) ([]ty
 			var ae smithy.APIError
 			if errors.As(err, &ae) {
 				if ae.ErrorCode() == DryRunOperation {
+					if dryRun {
+						return dryRunStateChanges(instanceIDs, types.InstanceStateNamePending), nil
+					}
 					// Let's now set dry run to be false. This will allow us to start the instances
 					input.DryRun = aws.Bool(false)
 					result, err = svc.StartInstances(ctx, input)
@@ -254,6 +481,9 @@ func StartStopInstance(region string, action string, instanceIDs []string) ([]ty
 			var ae smithy.APIError
 			if errors.As(err, &ae) {
 				if ae.ErrorCode() == DryRunOperation {
+					if dryRun {
+						return dryRunStateChanges(instanceIDs, types.InstanceStateNameStopping), nil
+					}
 					// Let's now set dry run to be false. This will allow us to start the instances
 					input.DryRun = aws.Bool(false)
 					result, err = svc.StopInstances(ctx, input)
@@ -269,83 +499,546 @@ func StartStopInstance(region string, action string, instanceIDs []string) ([]ty
 	}
 }
 
-// ModifyInstanceType modifies an AWS Instance type
-func ModifyInstanceType(region string, instanceType string, instanceID string) (err error) {
-	ctx := context.TODO()
+// WaitForInstances blocks until the instances reach the state targeted by the
+// action (running for start, stopped for stop and hibernate) or maxWait elapses
+func WaitForInstances(ctx context.Context, creds Credentials, region string, action string, instanceIDs []string, maxWait time.Duration) error {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	input := &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}
+
+	switch action {
+	case InstanceStart:
+		return ec2.NewInstanceRunningWaiter(svc).Wait(ctx, input, maxWait)
+	case InstanceStop, InstanceHibernate:
+		return ec2.NewInstanceStoppedWaiter(svc).Wait(ctx, input, maxWait)
+	default:
+		return errors.New("invalid action")
+	}
+}
+
+// statePollInterval is how often WaitForInstanceStates describes the instances
+const statePollInterval = 15 * time.Second
 
-	// Config sources can be passed to LoadDefaultConfig, these sources can implement
-	// one or more provider interfaces. These sources take priority over the standard
-	// environment and shared configuration values.
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-	)
+// WaitForInstanceStates polls the instances of a region until each reaches
+// the state targeted by the action, calling done with each instance as soon
+// as it does, or with an error once it is in a state it won't reach the
+// target from (e.g. terminated). A single DescribeInstances poll covers all
+// the instances, rather than one per instance, to stay clear of the EC2 API
+// rate limits. If ctx ends first, its error is returned and the instances
+// still pending aren't passed to done.
+func WaitForInstanceStates(ctx context.Context, creds Credentials, region string, action string, instanceIDs []string, done func(id string, err error)) error {
+	cfg, err := loadConfig(ctx, region, creds)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	// The states the waiters of the SDK give up on
+	target := TargetState(action)
+	failStates := []types.InstanceStateName{types.InstanceStateNamePending, types.InstanceStateNameTerminated}
+	if target == types.InstanceStateNameRunning {
+		failStates = []types.InstanceStateName{types.InstanceStateNameShuttingDown, types.InstanceStateNameTerminated, types.InstanceStateNameStopping}
+	}
+
+	pending := slices.Clone(instanceIDs)
+	for {
+		Logger.Debug("polling instance states", "region", region, "instances", pending, "target", target)
+		paginator := ec2.NewDescribeInstancesPaginator(svc, &ec2.DescribeInstancesInput{InstanceIds: pending})
+		states := make(map[string]types.InstanceStateName, len(pending))
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			// Instances that were just acted on may not be visible yet
+			var ae smithy.APIError
+			if errors.As(err, &ae) && ae.ErrorCode() == "InvalidInstanceID.NotFound" {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			for _, res := range page.Reservations {
+				for _, inst := range res.Instances {
+					if inst.State != nil {
+						states[aws.ToString(inst.InstanceId)] = inst.State.Name
+					}
+				}
+			}
+		}
+
+		pending = slices.DeleteFunc(pending, func(id string) bool {
+			switch state := states[id]; {
+			case state == target:
+				done(id, nil)
+				return true
+			case slices.Contains(failStates, state):
+				done(id, fmt.Errorf("instance is %s", state))
+				return true
+			}
+			return false
+		})
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(statePollInterval):
+		}
+	}
+}
+
+// ModifyInstanceType modifies an AWS Instance type. If dryRun is set, only the
+// permission check is performed. Errors are returned as *InstanceOpError values.
+func ModifyInstanceType(ctx context.Context, creds Credentials, region string, instanceType string, instanceID string, dryRun bool) (err error) {
+	defer func() {
+		err = newInstanceOpError("modify", region, []string{instanceID}, err)
+	}()
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
 	}
 	// Create new EC2 client
 	svc := ec2.NewFromConfig(cfg)
 
 	// This modifies the instance type of the specified instance
-	input := &ec2.ModifyInstanceAttributeInput{
+	return modifyInstanceAttribute(ctx, svc, &ec2.ModifyInstanceAttributeInput{
 		InstanceId: aws.String(instanceID),
 		InstanceType: &types.AttributeValue{
 			Value: aws.String(instanceType),
 		},
-		DryRun: aws.Bool(true),
+	}, dryRun)
+}
+
+// InstanceAttributes holds the attributes of an instance to modify. Only the
+// attributes that are set are modified.
+type InstanceAttributes struct {
+	EBSOptimized          *bool
+	ShutdownBehavior      *string
+	DisableAPITermination *bool
+}
+
+// ModifyInstanceAttributes modifies the set attributes of an instance. EC2
+// modifies one attribute per call, so each attribute is checked and applied
+// in turn. If dryRun is set, only the permission checks are performed.
+// Errors are returned as *InstanceOpError values.
+func ModifyInstanceAttributes(ctx context.Context, creds Credentials, region string, instanceID string, attrs InstanceAttributes, dryRun bool) (err error) {
+	defer func() {
+		err = newInstanceOpError("modify", region, []string{instanceID}, err)
+	}()
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
 	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
 
-	_, err = svc.ModifyInstanceAttribute(ctx, input)
+	var inputs []*ec2.ModifyInstanceAttributeInput
+	if attrs.EBSOptimized != nil {
+		inputs = append(inputs, &ec2.ModifyInstanceAttributeInput{
+			InstanceId:   aws.String(instanceID),
+			EbsOptimized: &types.AttributeBooleanValue{Value: attrs.EBSOptimized},
+		})
+	}
+	if attrs.ShutdownBehavior != nil {
+		inputs = append(inputs, &ec2.ModifyInstanceAttributeInput{
+			InstanceId:                        aws.String(instanceID),
+			InstanceInitiatedShutdownBehavior: &types.AttributeValue{Value: attrs.ShutdownBehavior},
+		})
+	}
+	if attrs.DisableAPITermination != nil {
+		inputs = append(inputs, &ec2.ModifyInstanceAttributeInput{
+			InstanceId:            aws.String(instanceID),
+			DisableApiTermination: &types.AttributeBooleanValue{Value: attrs.DisableAPITermination},
+		})
+	}
+	for _, input := range inputs {
+		if err := modifyInstanceAttribute(ctx, svc, input, dryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modifyInstanceAttribute checks the permissions to modify an instance
+// attribute with a dry run, then modifies it unless dryRun is set
+func modifyInstanceAttribute(ctx context.Context, svc *ec2.Client, input *ec2.ModifyInstanceAttributeInput, dryRun bool) error {
+	input.DryRun = aws.Bool(true)
+	_, err := svc.ModifyInstanceAttribute(ctx, input)
 	// If the error code is `DryRunOperation` it means we have the necessary
-	// permissions to Start this instance
+	// permissions to modify this instance
 	if err != nil {
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
 			if ae.ErrorCode() == DryRunOperation {
-				// Let's now set dry run to be false. This will allow us to start the instances
+				if dryRun {
+					return nil
+				}
+				// Let's now set dry run to be false. This will allow us to modify the instance
 				input.DryRun = aws.Bool(false)
 				_, err = svc.ModifyInstanceAttribute(ctx, input)
 			}
 		}
 	}
+	return err
+}
 
+// TerminateInstances terminates AWS Instances. If dryRun is set, only the
+// permission check is performed. Errors are returned as *InstanceOpError values.
+func TerminateInstances(ctx context.Context, creds Credentials, region string, instances []string, dryRun bool) (err error) {
+	defer func() {
+		err = newInstanceOpError("terminate", region, instances, err)
+	}()
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	_, err = svc.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: instances,
+		DryRun:      aws.Bool(dryRun),
+	})
+	// If the error code is `DryRunOperation` it means we have the necessary
+	// permissions to terminate the instances
+	if dryRun && err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == DryRunOperation {
+			return nil
+		}
+	}
 	return
 }
 
-func TerminateInstances(region string, instances []string) (err error) {
-	ctx := context.TODO()
+// CreateImage creates an AMI from an AWS Instance and returns the new image ID.
+// Unless noReboot is set, the instance is rebooted to ensure a consistent image.
+func CreateImage(ctx context.Context, creds Credentials, region string, instanceID string, name string, noReboot bool) (string, error) {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return "", err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	result, err := svc.CreateImage(ctx, &ec2.CreateImageInput{
+		InstanceId: aws.String(instanceID),
+		Name:       aws.String(name),
+		NoReboot:   aws.Bool(noReboot),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(result.ImageId), nil
+}
+
+// CreateTags adds or overwrites the given tags on AWS Instances
+func CreateTags(ctx context.Context, creds Credentials, region string, instanceIDs []string, tags map[string]string) (err error) {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
 
-	// Config sources can be passed to LoadDefaultConfig, these sources can implement
-	// one or more provider interfaces. These sources take priority over the standard
-	// environment and shared configuration values.
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-	)
+	ec2Tags := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		ec2Tags = append(ec2Tags, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+
+	_, err = svc.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: instanceIDs,
+		Tags:      ec2Tags,
+	})
+	return
+}
+
+// DeleteTags removes the tags with the given keys from AWS Instances,
+// regardless of their values. Keys that are not present are ignored.
+func DeleteTags(ctx context.Context, creds Credentials, region string, instanceIDs []string, keys []string) (err error) {
+	cfg, err := loadConfig(ctx, region, creds)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Create new EC2 client
 	svc := ec2.NewFromConfig(cfg)
 
-	_, err = svc.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
-		InstanceIds: instances,
+	ec2Tags := make([]types.Tag, 0, len(keys))
+	for _, k := range keys {
+		ec2Tags = append(ec2Tags, types.Tag{
+			Key: aws.String(k),
+		})
+	}
+
+	_, err = svc.DeleteTags(ctx, &ec2.DeleteTagsInput{
+		Resources: instanceIDs,
+		Tags:      ec2Tags,
 	})
 	return
 }
 
-func getSpotRequestType(requests []types.SpotInstanceRequest, id *string) types.SpotInstanceType {
-	for _, request := range requests {
-		if *request.SpotInstanceRequestId == *id {
-			return request.Type
+// The platforms an instance can have
+const (
+	PlatformLinux   = "linux"
+	PlatformWindows = "windows"
+)
+
+// ActionStates returns the instance states an action can be applied to
+func ActionStates(action string) []types.InstanceStateName {
+	switch action {
+	case InstanceStop, InstanceHibernate:
+		return []types.InstanceStateName{
+			types.InstanceStateNameRunning,
+		}
+	case InstanceStart:
+		return []types.InstanceStateName{
+			types.InstanceStateNameStopped,
+		}
+	default:
+		return []types.InstanceStateName{
+			types.InstanceStateNamePending,
+			types.InstanceStateNameRunning,
+			types.InstanceStateNameShuttingDown,
+			types.InstanceStateNameStopping,
+			types.InstanceStateNameStopped,
+		}
+	}
+}
+
+// TargetState returns the state instances reach once an action completes
+func TargetState(action string) types.InstanceStateName {
+	if action == InstanceStart {
+		return types.InstanceStateNameRunning
+	}
+	return types.InstanceStateNameStopped
+}
+
+// dryRunStateChanges describes the state changes a dry run would have requested
+func dryRunStateChanges(instanceIDs []string, state types.InstanceStateName) []types.InstanceStateChange {
+	changes := make([]types.InstanceStateChange, 0, len(instanceIDs))
+	for _, id := range instanceIDs {
+		changes = append(changes, types.InstanceStateChange{
+			InstanceId:   aws.String(id),
+			CurrentState: &types.InstanceState{Name: state},
+		})
+	}
+	return changes
+}
+
+// maxStatusInstanceIDs is the maximum number of instance IDs a single
+// DescribeInstanceStatus call accepts
+const maxStatusInstanceIDs = 100
+
+// getHealthStatuses returns the combined result of the system and instance
+// status checks of the given instances, keyed by instance ID
+func getHealthStatuses(ctx context.Context, svc ec2.DescribeInstanceStatusAPIClient, instanceIDs []string) (map[string]string, error) {
+	statuses := make(map[string]string, len(instanceIDs))
+	for start := 0; start < len(instanceIDs); start += maxStatusInstanceIDs {
+		end := min(start+maxStatusInstanceIDs, len(instanceIDs))
+		paginator := ec2.NewDescribeInstanceStatusPaginator(svc, &ec2.DescribeInstanceStatusInput{
+			InstanceIds: instanceIDs[start:end],
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, status := range page.InstanceStatuses {
+				statuses[aws.ToString(status.InstanceId)] = healthStatus(status)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// healthStatus combines the system and instance status checks of an instance
+// into a single status, reporting the worst of the two
+func healthStatus(status types.InstanceStatus) string {
+	var system, instance types.SummaryStatus
+	if status.SystemStatus != nil {
+		system = status.SystemStatus.Status
+	}
+	if status.InstanceStatus != nil {
+		instance = status.InstanceStatus.Status
+	}
+	for _, s := range []types.SummaryStatus{
+		types.SummaryStatusImpaired,
+		types.SummaryStatusInsufficientData,
+		types.SummaryStatusInitializing,
+		types.SummaryStatusNotApplicable,
+	} {
+		if system == s || instance == s {
+			return string(s)
+		}
+	}
+	return string(system)
+}
+
+// maxFilterValues is the maximum number of values a single EC2 filter accepts
+const maxFilterValues = 200
+
+// getVolumeSizes returns the sizes in GiB of the given volumes, keyed by volume ID
+func getVolumeSizes(ctx context.Context, svc ec2.DescribeVolumesAPIClient, volumeIDs []string) (map[string]int, error) {
+	sizes := make(map[string]int, len(volumeIDs))
+	for start := 0; start < len(volumeIDs); start += maxFilterValues {
+		end := min(start+maxFilterValues, len(volumeIDs))
+		paginator := ec2.NewDescribeVolumesPaginator(svc, &ec2.DescribeVolumesInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("volume-id"),
+					Values: volumeIDs[start:end],
+				},
+			},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, volume := range page.Volumes {
+				sizes[aws.ToString(volume.VolumeId)] = int(aws.ToInt32(volume.Size))
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// ProtectedInstances returns the IDs of the given instances that have
+// termination protection enabled, which EC2 refuses to terminate
+func ProtectedInstances(ctx context.Context, creds Credentials, region string, instanceIDs []string) ([]string, error) {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return nil, err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	var protected []string
+	for _, id := range instanceIDs {
+		result, err := svc.DescribeInstanceAttribute(ctx, &ec2.DescribeInstanceAttributeInput{
+			InstanceId: aws.String(id),
+			Attribute:  types.InstanceAttributeNameDisableApiTermination,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.DisableApiTermination != nil && aws.ToBool(result.DisableApiTermination.Value) {
+			protected = append(protected, id)
+		}
+	}
+	return protected, nil
+}
+
+// DisableTerminationProtection turns off the termination protection of the
+// given instances, so that they can be terminated
+func DisableTerminationProtection(ctx context.Context, creds Credentials, region string, instanceIDs []string, dryRun bool) error {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	for _, id := range instanceIDs {
+		_, err := svc.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+			InstanceId:            aws.String(id),
+			DisableApiTermination: &types.AttributeBooleanValue{Value: aws.Bool(false)},
+			DryRun:                aws.Bool(dryRun),
+		})
+		// If the error code is `DryRunOperation` it means we have the
+		// necessary permissions to modify the instance
+		if err != nil {
+			var ae smithy.APIError
+			if dryRun && errors.As(err, &ae) && ae.ErrorCode() == DryRunOperation {
+				continue
+			}
+			return err
 		}
 	}
-	return ""
+	return nil
+}
+
+// IsTerminationProtectedError reports whether err is EC2 refusing to terminate
+// an instance because its termination protection is enabled
+func IsTerminationProtectedError(err error) bool {
+	var ae smithy.APIError
+	return errors.As(err, &ae) && ae.ErrorCode() == "OperationNotPermitted"
 }
 
-func getInstanceAZ(statuses []types.InstanceStatus, id *string) string {
-	for _, instance := range statuses {
-		if *instance.InstanceId == *id {
-			return *instance.AvailabilityZone
+// IsUnauthorizedError reports whether err is EC2 refusing a request because
+// the credentials aren't authorized for it, which is also how regions that
+// haven't been opted in to respond
+func IsUnauthorizedError(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	switch ae.ErrorCode() {
+	case "AuthFailure", "UnauthorizedOperation", "OptInRequired":
+		return true
+	}
+	return false
+}
+
+// CancelSpotRequests cancels the given spot requests, so that persistent
+// requests don't launch replacements for the instances being terminated
+func CancelSpotRequests(ctx context.Context, creds Credentials, region string, requestIDs []string, dryRun bool) error {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	_, err = svc.CancelSpotInstanceRequests(ctx, &ec2.CancelSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: requestIDs,
+		DryRun:                 aws.Bool(dryRun),
+	})
+	// If the error code is `DryRunOperation` it means we have the necessary
+	// permissions to cancel the requests
+	if dryRun && err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == DryRunOperation {
+			return nil
+		}
+	}
+	return err
+}
+
+// getSpotRequests returns the given spot requests, keyed by request ID. The
+// IDs are passed as a filter, in batches, so that requests that no longer
+// exist are left out instead of failing the lookup.
+func getSpotRequests(ctx context.Context, svc ec2.DescribeSpotInstanceRequestsAPIClient, requestIDs []string) (map[string]types.SpotInstanceRequest, error) {
+	requests := make(map[string]types.SpotInstanceRequest, len(requestIDs))
+	for start := 0; start < len(requestIDs); start += maxFilterValues {
+		end := min(start+maxFilterValues, len(requestIDs))
+		paginator := ec2.NewDescribeSpotInstanceRequestsPaginator(svc, &ec2.DescribeSpotInstanceRequestsInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("spot-instance-request-id"),
+					Values: requestIDs[start:end],
+				},
+			},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, request := range page.SpotInstanceRequests {
+				requests[aws.ToString(request.SpotInstanceRequestId)] = request
+			}
 		}
 	}
-	return ""
+	return requests, nil
 }
@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// stubEC2 serves canned describe responses, with DescribeInstances split
+// into one page per element of pages
+type stubEC2 struct {
+	pages    [][]types.Reservation
+	statuses []types.InstanceStatus
+	calls    int
+	filters  []types.Filter
+}
+
+func (s *stubEC2) DescribeInstances(_ context.Context, in *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	s.calls++
+	s.filters = in.Filters
+	page := 0
+	if in.NextToken != nil {
+		page, _ = strconv.Atoi(*in.NextToken)
+	}
+	out := &ec2.DescribeInstancesOutput{}
+	if page < len(s.pages) {
+		out.Reservations = s.pages[page]
+	}
+	if page+1 < len(s.pages) {
+		out.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return out, nil
+}
+
+func (s *stubEC2) DescribeInstanceStatus(context.Context, *ec2.DescribeInstanceStatusInput, ...func(*ec2.Options)) (*ec2.DescribeInstanceStatusOutput, error) {
+	return &ec2.DescribeInstanceStatusOutput{InstanceStatuses: s.statuses}, nil
+}
+
+func (s *stubEC2) DescribeVolumes(context.Context, *ec2.DescribeVolumesInput, ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	return &ec2.DescribeVolumesOutput{}, nil
+}
+
+func (s *stubEC2) DescribeSpotInstanceRequests(context.Context, *ec2.DescribeSpotInstanceRequestsInput, ...func(*ec2.Options)) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	return &ec2.DescribeSpotInstanceRequestsOutput{}, nil
+}
+
+// reservation returns a reservation holding the given instances
+func reservation(instances ...types.Instance) types.Reservation {
+	return types.Reservation{Instances: instances}
+}
+
+// runningInstance returns a running instance with the given ID
+func runningInstance(id string) types.Instance {
+	return types.Instance{
+		InstanceId: aws.String(id),
+		State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+	}
+}
+
+func TestDescribeInstancesPaginates(t *testing.T) {
+	svc := &stubEC2{
+		pages: [][]types.Reservation{
+			{reservation(runningInstance("i-0000000000000001"))},
+			{reservation(runningInstance("i-0000000000000002"))},
+		},
+	}
+
+	instances, err := describeInstances(context.Background(), svc, "us-east-1", Filter{})
+	if err != nil {
+		t.Fatalf("describeInstances returned error: %v", err)
+	}
+	if svc.calls != 2 {
+		t.Errorf("DescribeInstances called %d times, want 2", svc.calls)
+	}
+	ids := make(map[string]bool)
+	for _, i := range instances {
+		ids[i.ID] = true
+	}
+	for _, id := range []string{"i-0000000000000001", "i-0000000000000002"} {
+		if !ids[id] {
+			t.Errorf("instance %s from its page is missing", id)
+		}
+	}
+}
+
+func TestDescribeInstancesAZWithoutStatus(t *testing.T) {
+	inst := runningInstance("i-0000000000000001")
+	inst.Placement = &types.Placement{AvailabilityZone: aws.String("us-east-1b")}
+	// DescribeInstanceStatus returns nothing for the instance
+	svc := &stubEC2{pages: [][]types.Reservation{{reservation(inst)}}}
+
+	instances, err := describeInstances(context.Background(), svc, "us-east-1", Filter{})
+	if err != nil {
+		t.Fatalf("describeInstances returned error: %v", err)
+	}
+	if len(instances) != 1 {
+		t.Fatalf("got %d instances, want 1", len(instances))
+	}
+	if instances[0].AZ != "us-east-1b" {
+		t.Errorf("AZ = %q, want %q", instances[0].AZ, "us-east-1b")
+	}
+}
+
+func TestDescribeInstancesNilState(t *testing.T) {
+	// A partial response, without a state or a state reason code
+	partial := types.Instance{
+		InstanceId:  aws.String("i-0000000000000001"),
+		StateReason: &types.StateReason{},
+	}
+	svc := &stubEC2{pages: [][]types.Reservation{{reservation(partial, runningInstance("i-0000000000000002"))}}}
+
+	instances, err := describeInstances(context.Background(), svc, "us-east-1", Filter{})
+	if err != nil {
+		t.Fatalf("describeInstances returned error: %v", err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("got %d instances, want 2", len(instances))
+	}
+	for _, i := range instances {
+		if i.ID == "i-0000000000000001" && i.Status != "" {
+			t.Errorf("Status = %q, want it empty for an instance without a state", i.Status)
+		}
+	}
+}
+
+func TestDescribeInstancesPassesWildcards(t *testing.T) {
+	svc := &stubEC2{}
+	filter := Filter{Tags: map[string][]string{"Name": {"web-*"}}}
+
+	if _, err := describeInstances(context.Background(), svc, "us-east-1", filter); err != nil {
+		t.Fatalf("describeInstances returned error: %v", err)
+	}
+	for _, f := range svc.filters {
+		if aws.ToString(f.Name) == "tag:Name" {
+			if len(f.Values) != 1 || f.Values[0] != "web-*" {
+				t.Errorf("tag:Name filter values = %q, want [web-*]", f.Values)
+			}
+			return
+		}
+	}
+	t.Error("no tag:Name filter was passed to DescribeInstances")
+}
@@ -0,0 +1,10 @@
+package aws
+
+import (
+	"io"
+	"log/slog"
+)
+
+// Logger receives the diagnostic output of the package, such as the API calls
+// made and the filters they use. It discards everything by default.
+var Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -0,0 +1,145 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+)
+
+const (
+	// pricingRegion is the region the Pricing API is queried in, since it is
+	// only available in a few regions and returns prices for all of them
+	pricingRegion = "us-east-1"
+	// hoursPerMonth is the average number of hours in a month
+	hoursPerMonth = 730
+	// VariableCost is the cost shown for spot instances, whose price varies
+	VariableCost = "variable"
+)
+
+// PriceList looks up on-demand instance prices, caching them so that each
+// instance type and region is only fetched once
+type PriceList struct {
+	creds  Credentials
+	mu     sync.Mutex
+	prices map[string]float64
+}
+
+// NewPriceList returns a PriceList that queries the Pricing API with the given credentials
+func NewPriceList(creds Credentials) *PriceList {
+	return &PriceList{
+		creds:  creds,
+		prices: make(map[string]float64),
+	}
+}
+
+// HourlyPrice returns the on-demand hourly price in USD of a shared-tenancy
+// instance of the given type and platform (linux or windows) in a region.
+// Windows prices include the license.
+func (p *PriceList) HourlyPrice(ctx context.Context, region string, instanceType types.InstanceType, platform string) (float64, error) {
+	operatingSystem := "Linux"
+	if platform == PlatformWindows {
+		operatingSystem = "Windows"
+	}
+	key := region + "/" + string(instanceType) + "/" + operatingSystem
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if price, ok := p.prices[key]; ok {
+		return price, nil
+	}
+
+	cfg, err := loadConfig(ctx, pricingRegion, p.creds)
+	if err != nil {
+		return 0, err
+	}
+	svc := pricing.NewFromConfig(cfg)
+
+	termMatch := func(field, value string) pricingtypes.Filter {
+		return pricingtypes.Filter{
+			Type:  pricingtypes.FilterTypeTermMatch,
+			Field: aws.String(field),
+			Value: aws.String(value),
+		}
+	}
+	result, err := svc.GetProducts(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters: []pricingtypes.Filter{
+			termMatch("instanceType", string(instanceType)),
+			termMatch("regionCode", region),
+			termMatch("operatingSystem", operatingSystem),
+			termMatch("licenseModel", "No License required"),
+			termMatch("tenancy", "Shared"),
+			termMatch("preInstalledSw", "NA"),
+			termMatch("capacitystatus", "Used"),
+		},
+		MaxResults: aws.Int32(1),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(result.PriceList) == 0 {
+		return 0, fmt.Errorf("no price found for %s in %s", instanceType, region)
+	}
+
+	price, err := parseOnDemandPrice(result.PriceList[0])
+	if err != nil {
+		return 0, err
+	}
+	p.prices[key] = price
+	return price, nil
+}
+
+// AddCosts sets the estimated monthly cost of every running instance in the
+// account summary. Spot instances are marked as variable.
+func (u AccountSummary) AddCosts(ctx context.Context, prices *PriceList) error {
+	for _, region := range u {
+		for i := range region.Instances {
+			instance := &region.Instances[i]
+			if instance.Status != types.InstanceStateNameRunning {
+				continue
+			}
+			if instance.Lifecycle == string(types.InstanceLifecycleTypeSpot) {
+				instance.MonthlyCost = VariableCost
+				continue
+			}
+			price, err := prices.HourlyPrice(ctx, instance.Region, instance.Type, instance.Platform)
+			if err != nil {
+				return err
+			}
+			instance.MonthlyCost = fmt.Sprintf("$%.2f", price*hoursPerMonth)
+		}
+	}
+	return nil
+}
+
+// parseOnDemandPrice extracts the hourly USD price from a Pricing API product
+func parseOnDemandPrice(product string) (float64, error) {
+	var p struct {
+		Terms struct {
+			OnDemand map[string]struct {
+				PriceDimensions map[string]struct {
+					PricePerUnit map[string]string `json:"pricePerUnit"`
+				} `json:"priceDimensions"`
+			} `json:"OnDemand"`
+		} `json:"terms"`
+	}
+	if err := json.Unmarshal([]byte(product), &p); err != nil {
+		return 0, fmt.Errorf("cannot parse price list: %w", err)
+	}
+	for _, term := range p.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			if usd, ok := dimension.PricePerUnit["USD"]; ok {
+				return strconv.ParseFloat(usd, 64)
+			}
+		}
+	}
+	return 0, errors.New("no on-demand USD price in price list")
+}
@@ -0,0 +1,109 @@
+package aws
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// ProfileSummary is a structure holding the account summary of a named profile
+type ProfileSummary struct {
+	Profile string
+	Regions AccountSummary
+}
+
+// ProfileSummaries is a structure holding the account summaries of several named profiles
+type ProfileSummaries []ProfileSummary
+
+// Profiles returns the names of the profiles defined in the shared config and
+// credentials files, honouring the AWS_CONFIG_FILE and AWS_SHARED_CREDENTIALS_FILE
+// environment variables
+func Profiles() ([]string, error) {
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" {
+		configFile = config.DefaultSharedConfigFilename()
+	}
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFile == "" {
+		credentialsFile = config.DefaultSharedCredentialsFilename()
+	}
+
+	var profiles []string
+	for _, file := range []struct {
+		path string
+		// prefixed is set for the config file, where sections other than
+		// the default profile are named "profile <name>"
+		prefixed bool
+	}{
+		{configFile, true},
+		{credentialsFile, false},
+	} {
+		names, err := profileSections(file.path, file.prefixed)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !slices.Contains(profiles, name) {
+				profiles = append(profiles, name)
+			}
+		}
+	}
+	slices.Sort(profiles)
+	return profiles, nil
+}
+
+// profileSections returns the profile names of the sections in a shared
+// config or credentials file. A missing file has no profiles.
+func profileSections(path string, prefixed bool) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		section := strings.TrimSpace(line[1 : len(line)-1])
+		if prefixed && section != "default" {
+			name, ok := strings.CutPrefix(section, "profile ")
+			if !ok {
+				// Other sections, such as sso-session, aren't profiles
+				continue
+			}
+			section = strings.TrimSpace(name)
+		}
+		names = append(names, section)
+	}
+	return names, scanner.Err()
+}
+
+// WriteCSV writes the instances of every profile to w in CSV format, with the
+// profile name in the first column
+func (p ProfileSummaries) WriteCSV(w io.Writer) error {
+	var all []Instance
+	var labels []string
+	for _, profile := range p {
+		for _, region := range profile.Regions {
+			all = append(all, region.Instances...)
+			for range region.Instances {
+				labels = append(labels, profile.Profile)
+			}
+		}
+	}
+	return writeCSV(csv.NewWriter(w), all, "Profile", labels)
+}
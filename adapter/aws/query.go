@@ -0,0 +1,74 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// QueryConcurrency caps the number of regions queried at once so that large
+// accounts don't burst past the EC2 API rate limits
+var QueryConcurrency = 8
+
+// Filter selects the instances to query. Empty fields match every instance.
+// Multiple values of a field match instances with any of them, except for
+// TagKeys, where instances must have every one of the keys.
+type Filter struct {
+	Tags map[string][]string
+	// Action selects the default states when States is empty
+	Action            string
+	InstanceIDs       []string
+	States            []types.InstanceStateName
+	InstanceTypes     []string
+	Zones             []string
+	VpcIDs            []string
+	SubnetIDs         []string
+	TagKeys           []string
+	AMIIDs            []string
+	LaunchTemplateIDs []string
+	// Lifecycle is on-demand or one of the EC2 instance lifecycles, such
+	// as spot
+	Lifecycle string
+}
+
+// QueryAccount queries the instances matching the filter in each of the
+// regions concurrently and returns the regions that have any, sorted by
+// region. The summary holds every region that could be queried even when
+// others fail, in which case the error joins the failures of each region.
+//
+// If onRegion is set, it is called with the summary of each region as soon as
+// it has been queried, including the regions that failed with Err set, so
+// that callers can report progress or stream the results. It is only called
+// from the calling goroutine.
+func QueryAccount(ctx context.Context, creds Credentials, regions []string, filter Filter, onRegion func(RegionSummary)) (AccountSummary, error) {
+	Logger.Debug("querying regions", "regions", regions, "concurrency", QueryConcurrency)
+	c := make(chan RegionSummary)
+	sem := make(chan struct{}, max(QueryConcurrency, 1))
+	for _, r := range regions {
+		go func(r string) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			GetDeployedInstances(ctx, c, creds, r, filter)
+		}(r)
+	}
+
+	var accSum AccountSummary
+	var errs []error
+	for range regions {
+		regSum := <-c
+		if onRegion != nil {
+			onRegion(regSum)
+		}
+		if regSum.Err != nil {
+			errs = append(errs, fmt.Errorf("region %s: %w", regSum.Region, regSum.Err))
+			continue
+		}
+		if len(regSum.Instances) > 0 {
+			accSum = append(accSum, regSum)
+		}
+	}
+	accSum.Sort()
+	return accSum, errors.Join(errs...)
+}
@@ -1,15 +1,21 @@
 package aws
 
 import (
+	"bufio"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/smithy-go"
@@ -18,27 +24,124 @@ import (
 
 // RegionSummary is a structure holding deployed instances in a given region
 type RegionSummary struct {
-	Region    string
+	Region string
+	// AccountID is the ID of the account the region was queried in
+	AccountID string `json:",omitempty"`
 	Instances []Instance
+	// Err is set when the region could not be queried, to distinguish a
+	// failed region from one without any matching instances
+	Err error `json:"-" yaml:"-"`
 }
 
 // AccountSummary is a structure holding a slice of regions summaries across an entire account
 type AccountSummary []RegionSummary
 
-// Print prints the summary of instances in an account in tabular format
-func (u AccountSummary) Print() {
+// TableOptions controls how instances are rendered as a table
+type TableOptions struct {
+	// Wide includes the columns of the fields tagged with `table:"wide"`
+	Wide bool
+	// NoColor disables the ANSI styling of the table, e.g. when the output
+	// isn't a terminal or colors were turned off by the user
+	NoColor bool
+	// StatusStyle selects how statuses are rendered, defaulting to text
+	StatusStyle StatusStyle
+	// Columns selects and orders the instance fields rendered as columns,
+	// which are otherwise chosen by Wide
+	Columns []string
+	// Target adds a Change column after the Status column, showing the
+	// transition of each instance to this state, when set
+	Target types.InstanceStateName
+}
+
+// StatusStyle is a way of rendering instance statuses in a table
+type StatusStyle string
+
+const (
+	// StatusStyleText renders statuses as plain words, e.g. "running"
+	StatusStyleText StatusStyle = "text"
+	// StatusStyleEmoji prefixes statuses with an emoji marker
+	StatusStyleEmoji StatusStyle = "emoji"
+	// StatusStyleASCII prefixes statuses with an ASCII marker, e.g. "[+]"
+	StatusStyleASCII StatusStyle = "ascii"
+)
+
+// StatusStyles lists the supported status styles
+func StatusStyles() []StatusStyle {
+	return []StatusStyle{StatusStyleText, StatusStyleEmoji, StatusStyleASCII}
+}
+
+// Print writes the summary of instances in an account to w in tabular format
+func (u AccountSummary) Print(w io.Writer, opts TableOptions) {
 	for _, region := range u {
-		region.Print()
-		fmt.Println("")
+		region.Print(w, opts)
+		fmt.Fprintln(w, "")
 	}
 }
 
-// Prompt prompts user for confirmation
-func (u AccountSummary) Prompt(action string) AccountSummary {
+// WriteCSV writes the instances in an account summary to w in CSV format, with a
+// header row containing the instance field names
+func (u AccountSummary) WriteCSV(w io.Writer) error {
+	var all []Instance
+	for _, region := range u {
+		all = append(all, region.Instances...)
+	}
+	return writeCSV(csv.NewWriter(w), all, "", nil)
+}
+
+// WriteNDJSON writes the instances in an account summary to w as newline
+// delimited JSON, with one instance object per line
+func (u AccountSummary) WriteNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, region := range u {
+		for _, instance := range region.Instances {
+			if err := enc.Encode(instance); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeCSV writes the instances in CSV format, with a header row containing the
+// instance field names. If label is set, it names an extra first column
+// holding the corresponding entry of labels for each instance.
+func writeCSV(cw *csv.Writer, all []Instance, label string, labels []string) error {
+	structFields := tableFields(all, true)
+	header := make([]string, 0, len(structFields)+1)
+	if label != "" {
+		header = append(header, label)
+	}
+	for _, f := range structFields {
+		header = append(header, f.Name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i, o := range all {
+		row := make([]string, 0, len(header))
+		if label != "" {
+			row = append(row, labels[i])
+		}
+		for _, f := range structFields {
+			row = append(row, formatField(o, f.Name))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Prompt prompts user for confirmation, rendering the instances with the given table options.
+// If assumeYes is set, the instances are rendered and confirmed without reading any input.
+func (u AccountSummary) Prompt(action string, opts TableOptions, assumeYes bool) AccountSummary {
 	var s string
 
 	// Declare labels to print onto terminal
-	questionLabel := "\n" + "This command will " + action + " the following running instances matching the filter:\n"
+	questionLabel := "\n" + "This command will " + action + " the following instances matching the filter:\n"
 	confirmationLabel := "\nWould you like to proceed? [Y/n]"
 	errLabel := "No instances are available for " + action + " command.\n"
 
@@ -49,8 +152,13 @@ func (u AccountSummary) Prompt(action string) AccountSummary {
 	}
 	// If region summary exists in account summary, means there are matching instances, return as table
 	fmt.Println(questionLabel)
+	opts.Target = TargetState(action)
 	for _, regionSum := range u {
-		regionSum.Print()
+		regionSum.Print(os.Stdout, opts)
+	}
+	if assumeYes {
+		fmt.Println("\nProceeding without confirmation.")
+		return u
 	}
 	fmt.Println(confirmationLabel)
 
@@ -68,6 +176,108 @@ func (u AccountSummary) Prompt(action string) AccountSummary {
 	return AccountSummary{}
 }
 
+// Pick lists the instances in the account summary and lets the user choose
+// which of them the action should apply to, by entering their numbers (e.g.
+// "1,3,5-7") or "all". It returns an account summary holding only the
+// chosen instances.
+func (u AccountSummary) Pick(action string) AccountSummary {
+	errLabel := "No instances are available for " + action + " command.\n"
+	if len(u) == 0 {
+		fmt.Print(errLabel)
+		os.Exit(0)
+	}
+
+	// Number the instances across all regions
+	var choices []Instance
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "Name", "ID", "Type", "Status", "Region"})
+	for _, regionSum := range u {
+		for _, instance := range regionSum.Instances {
+			choices = append(choices, instance)
+			table.Append([]string{
+				strconv.Itoa(len(choices)),
+				instance.Name,
+				instance.ID,
+				string(instance.Type),
+				string(instance.Status),
+				instance.Region,
+			})
+		}
+	}
+	fmt.Println("\nSelect the instances to " + action + ":")
+	table.Render()
+	fmt.Print("\nEnter instance numbers (e.g. 1,3,5-7) or 'all': ")
+
+	// Scan terminal for input
+	text, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		fmt.Println("cannot read input:", err)
+		os.Exit(1)
+	}
+	numbers, err := parseSelection(strings.TrimSpace(text), len(choices))
+	if err != nil {
+		fmt.Println("invalid selection:", err)
+		os.Exit(1)
+	}
+	selected := make(map[string]bool, len(numbers))
+	for _, n := range numbers {
+		selected[choices[n-1].ID] = true
+	}
+
+	var picked AccountSummary
+	for _, regionSum := range u {
+		var instances []Instance
+		for _, instance := range regionSum.Instances {
+			if selected[instance.ID] {
+				instances = append(instances, instance)
+			}
+		}
+		if len(instances) > 0 {
+			picked = append(picked, RegionSummary{Region: regionSum.Region, Instances: instances})
+		}
+	}
+	return picked
+}
+
+// parseSelection parses a list of 1-based choice numbers and ranges, such as
+// "1,3,5-7", or "all", and returns the selected choices
+func parseSelection(text string, n int) ([]int, error) {
+	if strings.EqualFold(text, "all") {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i + 1
+		}
+		return all, nil
+	}
+
+	var selected []int
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", part)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.Atoi(strings.TrimSpace(hi))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a valid range", part)
+			}
+		}
+		if first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("%q is out of range 1-%d", part, n)
+		}
+		for i := first; i <= last; i++ {
+			selected = append(selected, i)
+		}
+	}
+	return selected, nil
+}
+
 // GetInstanceRegion returns the region of an instance given an account summary
 func GetInstanceRegion(accSum AccountSummary, id string) (string, error) {
 	for _, region := range accSum {
@@ -80,18 +290,21 @@ func GetInstanceRegion(accSum AccountSummary, id string) (string, error) {
 	return "", errors.New("instance not found")
 }
 
-// Print prints the summary of instances in a given region in tabular format
-func (u RegionSummary) Print() {
-	fmt.Println(u.Region)
-	WriteTable(u.Instances)
+// Print writes the summary of instances in a given region to w in tabular format
+func (u RegionSummary) Print(w io.Writer, opts TableOptions) {
+	if u.AccountID != "" {
+		fmt.Fprintf(w, "%s (account %s)\n", u.Region, u.AccountID)
+	} else {
+		fmt.Fprintln(w, u.Region)
+	}
+	WriteTable(w, u.Instances, opts)
 }
 
 // GetRegions is a function to retrieve all active regions in an account
-func GetRegions() (regions []string) {
-	ctx := context.TODO()
-	cfg, err := config.LoadDefaultConfig(ctx)
+func GetRegions(ctx context.Context, creds Credentials) ([]string, error) {
+	cfg, err := loadConfig(ctx, "", creds)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	svc := ec2.NewFromConfig(cfg)
 	input := &ec2.DescribeRegionsInput{
@@ -110,16 +323,17 @@ func GetRegions() (regions []string) {
 	if err != nil {
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
-			log.Printf("code: %s, message: %s, fault: %s", ae.ErrorCode(), ae.ErrorMessage(), ae.ErrorFault().String())
+			return nil, fmt.Errorf("code: %s, message: %s, fault: %s", ae.ErrorCode(), ae.ErrorMessage(), ae.ErrorFault().String())
 		}
-		return
+		return nil, err
 	}
 
+	regions := make([]string, 0, len(result.Regions))
 	for _, r := range result.Regions {
 		regions = append(regions, *r.RegionName)
 	}
 
-	return regions
+	return regions, nil
 }
 
 // Helper function to extract instance IDs from a slice of instances
@@ -131,25 +345,42 @@ func IDs(instances []Instance) []string {
 	return ids
 }
 
-func WriteTable(data []Instance) {
-	table := tablewriter.NewWriter(os.Stdout)
+// WriteTable writes the instances to w as a table
+func WriteTable(w io.Writer, data []Instance, opts TableOptions) {
+	table := tablewriter.NewWriter(w)
 
-	structFields := reflect.VisibleFields(reflect.TypeOf(data[0]))
+	structFields := tableFields(data, opts.Wide)
+	if len(opts.Columns) > 0 {
+		structFields = columnFields(opts.Columns)
+	}
 	header := make([]string, 0, len(structFields))
 	headerColors := make([]tablewriter.Colors, 0, len(structFields))
 	for _, f := range structFields {
 		header = append(header, f.Name)
 		headerColors = append(headerColors, tablewriter.Colors{tablewriter.Bold})
+		if f.Name == "LaunchTime" {
+			header = append(header, "Uptime")
+			headerColors = append(headerColors, tablewriter.Colors{tablewriter.Bold})
+		}
+		if f.Name == "Status" && opts.Target != "" {
+			header = append(header, "Change")
+			headerColors = append(headerColors, tablewriter.Colors{tablewriter.Bold})
+		}
 	}
 	table.SetHeader(header)
-	table.SetHeaderColor(headerColors...)
+	if !opts.NoColor {
+		table.SetHeaderColor(headerColors...)
+	}
 
 	for _, o := range data {
 		var row []string
 		var rowColor []tablewriter.Colors
 		for _, f := range structFields {
-			value := fmt.Sprintf("%v", reflect.ValueOf(o).FieldByName(f.Name).Interface())
-			row = append(row, value)
+			if f.Name == "Status" {
+				row = append(row, formatStatus(o.Status, opts.StatusStyle))
+			} else {
+				row = append(row, formatField(o, f.Name))
+			}
 			switch f.Name {
 			case "Name":
 				rowColor = append(rowColor, tablewriter.Colors{tablewriter.Bold})
@@ -166,12 +397,185 @@ func WriteTable(data []Instance) {
 				default:
 					rowColor = append(rowColor, tablewriter.Colors{})
 				}
+				if opts.Target != "" {
+					row = append(row, transition(o, opts.Target))
+					if o.Status == opts.Target {
+						rowColor = append(rowColor, tablewriter.Colors{tablewriter.FgYellowColor})
+					} else {
+						rowColor = append(rowColor, tablewriter.Colors{})
+					}
+				}
+			case "HealthStatus":
+				switch types.SummaryStatus(o.HealthStatus) {
+				case types.SummaryStatusOk:
+					rowColor = append(rowColor, tablewriter.Colors{tablewriter.FgGreenColor})
+				case types.SummaryStatusImpaired:
+					rowColor = append(rowColor, tablewriter.Colors{tablewriter.FgRedColor})
+				case types.SummaryStatusInitializing:
+					rowColor = append(rowColor, tablewriter.Colors{tablewriter.FgYellowColor})
+				default:
+					rowColor = append(rowColor, tablewriter.Colors{})
+				}
+			case "LaunchTime":
+				rowColor = append(rowColor, tablewriter.Colors{})
+				row = append(row, uptime(o))
+				rowColor = append(rowColor, tablewriter.Colors{})
 			default:
 				rowColor = append(rowColor, tablewriter.Colors{})
 			}
 		}
-		table.Rich(row, rowColor)
+		if opts.NoColor {
+			table.Append(row)
+		} else {
+			table.Rich(row, rowColor)
+		}
 	}
 
 	table.Render()
 }
+
+// transition describes the change of an instance to the target state, e.g.
+// "running → stopped", flagging instances already in that state as no-ops
+func transition(o Instance, target types.InstanceStateName) string {
+	if o.Status == target {
+		return "no-op (already " + string(target) + ")"
+	}
+	return string(o.Status) + " → " + string(target)
+}
+
+// instanceFields returns the instance fields that can be rendered as columns,
+// skipping the fields tagged with `table:"-"`
+func instanceFields() []reflect.StructField {
+	var fields []reflect.StructField
+	for _, f := range reflect.VisibleFields(reflect.TypeOf(Instance{})) {
+		if hasTableOption(f, "-") {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// tableFields returns the instance fields rendered as columns for the given
+// instances. Fields tagged with `table:"wide"` are only included in wide
+// tables, and fields tagged with `table:",omitempty"` are only included if at
+// least one of the instances has a non-zero value for them.
+func tableFields(data []Instance, wide bool) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, f := range instanceFields() {
+		if hasTableOption(f, "wide") && !wide {
+			continue
+		}
+		if hasTableOption(f, "omitempty") && !anyNonZero(data, f) {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// ColumnNames resolves column names, matched case-insensitively against the
+// instance fields that can be rendered as columns, to the field names
+func ColumnNames(columns []string) ([]string, error) {
+	fields := instanceFields()
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		i := slices.IndexFunc(fields, func(f reflect.StructField) bool {
+			return strings.EqualFold(f.Name, strings.TrimSpace(column))
+		})
+		if i < 0 {
+			known := make([]string, 0, len(fields))
+			for _, f := range fields {
+				known = append(known, f.Name)
+			}
+			return nil, fmt.Errorf("invalid column %q: must be one of %s", column, strings.Join(known, ", "))
+		}
+		names = append(names, fields[i].Name)
+	}
+	return names, nil
+}
+
+// columnFields returns the instance fields with the given names, in order
+func columnFields(names []string) []reflect.StructField {
+	t := reflect.TypeOf(Instance{})
+	fields := make([]reflect.StructField, 0, len(names))
+	for _, name := range names {
+		if f, ok := t.FieldByName(name); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// hasTableOption reports whether the field's table tag contains the option
+func hasTableOption(f reflect.StructField, option string) bool {
+	return slices.Contains(strings.Split(f.Tag.Get("table"), ","), option)
+}
+
+// anyNonZero reports whether any of the instances has a non-zero value for the field
+func anyNonZero(data []Instance, f reflect.StructField) bool {
+	for _, o := range data {
+		if !reflect.ValueOf(o).FieldByIndex(f.Index).IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// formatField returns the display value of the named field of an instance
+func formatField(o Instance, name string) string {
+	switch v := reflect.ValueOf(o).FieldByName(name).Interface().(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatStatus renders an instance status in the given style
+func formatStatus(status types.InstanceStateName, style StatusStyle) string {
+	var emoji, ascii string
+	switch status {
+	case types.InstanceStateNameRunning:
+		emoji, ascii = "✅", "[+]"
+	case types.InstanceStateNameStopped, "hibernated":
+		emoji, ascii = "⛔", "[-]"
+	case types.InstanceStateNamePending, types.InstanceStateNameStopping, types.InstanceStateNameShuttingDown:
+		emoji, ascii = "⏳", "[~]"
+	case types.InstanceStateNameTerminated:
+		emoji, ascii = "⚫", "[x]"
+	default:
+		emoji, ascii = "❔", "[?]"
+	}
+	switch style {
+	case StatusStyleEmoji:
+		return emoji + " " + string(status)
+	case StatusStyleASCII:
+		return ascii + " " + string(status)
+	default:
+		return string(status)
+	}
+}
+
+// uptime returns a human-friendly duration (e.g. "3d4h") since a running
+// instance was launched, or an empty string for instances that aren't running
+func uptime(o Instance) string {
+	if o.Status != types.InstanceStateNameRunning || o.LaunchTime.IsZero() {
+		return ""
+	}
+	d := time.Since(o.LaunchTime)
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
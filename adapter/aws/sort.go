@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// uptimeSortField is the computed uptime column, which sorts in the opposite
+// order to the launch time
+const uptimeSortField = "Uptime"
+
+// SortBy sorts the instances within each region by the field named in spec,
+// which may be suffixed with ":desc" to sort in descending order (e.g.
+// "Type" or "LaunchTime:desc"). Field names are matched case-insensitively
+// against the Instance struct, and "Uptime" is also accepted.
+func (u AccountSummary) SortBy(spec string) error {
+	less, err := instanceLess(spec)
+	if err != nil {
+		return err
+	}
+	for _, region := range u {
+		instances := region.Instances
+		sort.SliceStable(instances, func(i, j int) bool {
+			return less(instances[i], instances[j])
+		})
+	}
+	return nil
+}
+
+// Sort sorts the regions of the account summary by name, so output doesn't
+// depend on the order the regions were queried in
+func (u AccountSummary) Sort() {
+	sort.Slice(u, func(i, j int) bool {
+		return u[i].Region < u[j].Region
+	})
+}
+
+// ValidateSort checks that spec names a field instances can be sorted by
+func ValidateSort(spec string) error {
+	_, err := instanceLess(spec)
+	return err
+}
+
+// instanceLess returns the ordering function for the sort spec
+func instanceLess(spec string) (func(a, b Instance) bool, error) {
+	name, order, _ := strings.Cut(spec, ":")
+	var desc bool
+	switch strings.ToLower(order) {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		return nil, fmt.Errorf("invalid sort order %q: must be asc or desc", order)
+	}
+
+	if strings.EqualFold(name, uptimeSortField) {
+		name = "LaunchTime"
+		desc = !desc
+	}
+
+	var field reflect.StructField
+	var found bool
+	var names []string
+	for _, f := range instanceFields() {
+		names = append(names, f.Name)
+		if strings.EqualFold(f.Name, name) {
+			field, found = f, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("cannot sort by unknown field %q (valid fields: %s, %s)", name, strings.Join(names, ", "), uptimeSortField)
+	}
+
+	return func(a, b Instance) bool {
+		if desc {
+			a, b = b, a
+		}
+		return compareField(reflect.ValueOf(a).FieldByIndex(field.Index), reflect.ValueOf(b).FieldByIndex(field.Index))
+	}, nil
+}
+
+// compareField reports whether the field value a sorts before b
+func compareField(a, b reflect.Value) bool {
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Before(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SpotPriceWarning describes a spot instance whose spot request pays less than
+// the current spot price, so that it is unlikely to start
+type SpotPriceWarning struct {
+	Instance     Instance
+	MaxPrice     float64
+	CurrentPrice float64
+}
+
+// SpotPricesAboveMax returns a warning for every spot instance whose current
+// spot price is above the max price of its spot request. Instances without a
+// max price, or whose current price can't be found, are left out.
+func SpotPricesAboveMax(ctx context.Context, creds Credentials, region string, instances []Instance) ([]SpotPriceWarning, error) {
+	cfg, err := loadConfig(ctx, region, creds)
+	if err != nil {
+		return nil, err
+	}
+	// Create new EC2 client
+	svc := ec2.NewFromConfig(cfg)
+
+	// Instances of the same type in the same zone share a spot price
+	type market struct {
+		instanceType types.InstanceType
+		zone         string
+		product      string
+	}
+	prices := make(map[market]float64)
+
+	var warnings []SpotPriceWarning
+	for _, instance := range instances {
+		if instance.SpotMaxPrice == "" {
+			continue
+		}
+		maxPrice, err := strconv.ParseFloat(instance.SpotMaxPrice, 64)
+		if err != nil {
+			continue
+		}
+		m := market{instance.Type, instance.AZ, spotProduct(instance)}
+		price, ok := prices[m]
+		if !ok {
+			price, err = currentSpotPrice(ctx, svc, m.instanceType, m.zone, m.product)
+			if err != nil {
+				return nil, err
+			}
+			prices[m] = price
+		}
+		if price > maxPrice {
+			warnings = append(warnings, SpotPriceWarning{Instance: instance, MaxPrice: maxPrice, CurrentPrice: price})
+		}
+	}
+	return warnings, nil
+}
+
+// spotProduct returns the product description spot prices are listed under
+// for an instance
+func spotProduct(instance Instance) string {
+	if instance.PlatformDetails != "" {
+		return instance.PlatformDetails
+	}
+	if instance.Platform == PlatformWindows {
+		return "Windows"
+	}
+	return "Linux/UNIX"
+}
+
+// currentSpotPrice returns the most recent spot price of an instance type in
+// an availability zone, or zero if there is none
+func currentSpotPrice(ctx context.Context, svc *ec2.Client, instanceType types.InstanceType, zone string, product string) (float64, error) {
+	// A start time of now returns the price in effect now
+	result, err := svc.DescribeSpotPriceHistory(ctx, &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       []types.InstanceType{instanceType},
+		AvailabilityZone:    aws.String(zone),
+		ProductDescriptions: []string{product},
+		StartTime:           aws.Time(time.Now()),
+	})
+	if err != nil {
+		return 0, err
+	}
+	var latest types.SpotPrice
+	for _, price := range result.SpotPriceHistory {
+		if latest.Timestamp == nil || (price.Timestamp != nil && price.Timestamp.After(*latest.Timestamp)) {
+			latest = price
+		}
+	}
+	if latest.SpotPrice == nil {
+		return 0, nil
+	}
+	return strconv.ParseFloat(*latest.SpotPrice, 64)
+}
@@ -0,0 +1,155 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Stats holds instance counts aggregated across all regions of an account summary
+type Stats struct {
+	Total       int
+	ByStatus    map[string]int
+	ByLifecycle map[string]int
+}
+
+// Stats counts the instances in the account summary by status and lifecycle
+func (u AccountSummary) Stats() Stats {
+	stats := Stats{
+		ByStatus:    make(map[string]int),
+		ByLifecycle: make(map[string]int),
+	}
+	for _, region := range u {
+		for _, instance := range region.Instances {
+			stats.Total++
+			stats.ByStatus[string(instance.Status)]++
+			stats.ByLifecycle[instance.Lifecycle]++
+		}
+	}
+	return stats
+}
+
+// String renders the stats as a single line, e.g.
+// "Total: 12 instances | running: 5, stopped: 7 | on-demand: 10, spot: 2"
+func (s Stats) String() string {
+	noun := "instances"
+	if s.Total == 1 {
+		noun = "instance"
+	}
+	parts := []string{fmt.Sprintf("Total: %d %s", s.Total, noun)}
+	if len(s.ByStatus) > 0 {
+		parts = append(parts, formatCounts(s.ByStatus))
+	}
+	if len(s.ByLifecycle) > 0 {
+		parts = append(parts, formatCounts(s.ByLifecycle))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// PrintStats writes the one-line stats rollup of the account summary to w
+func (u AccountSummary) PrintStats(w io.Writer) {
+	fmt.Fprintln(w, u.Stats())
+}
+
+// formatCounts renders counts as comma-separated "key: count" pairs in key order
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s: %d", k, counts[k]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// GroupCount holds the number of instances sharing a value of a field, and how
+// many of them are running
+type GroupCount struct {
+	Value   string
+	Count   int
+	Running int
+}
+
+// groupDimensions maps the dimensions instances can be grouped by to the
+// field value they are grouped on
+var groupDimensions = map[string]func(Instance) string{
+	"type":        func(i Instance) string { return string(i.Type) },
+	"az":          func(i Instance) string { return i.AZ },
+	"environment": func(i Instance) string { return i.Environment },
+	"lifecycle":   func(i Instance) string { return i.Lifecycle },
+	"state":       func(i Instance) string { return string(i.Status) },
+}
+
+// GroupDimensions returns the names of the dimensions instances can be
+// grouped by, in alphabetical order
+func GroupDimensions() []string {
+	names := make([]string, 0, len(groupDimensions))
+	for name := range groupDimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GroupBy counts the instances in the account summary by the value of a
+// dimension, most common first
+func (u AccountSummary) GroupBy(dimension string) ([]GroupCount, error) {
+	value, ok := groupDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("invalid dimension %q: must be one of %s", dimension, strings.Join(GroupDimensions(), ", "))
+	}
+	index := make(map[string]int)
+	var counts []GroupCount
+	for _, region := range u {
+		for _, instance := range region.Instances {
+			v := value(instance)
+			n, ok := index[v]
+			if !ok {
+				n = len(counts)
+				index[v] = n
+				counts = append(counts, GroupCount{Value: v})
+			}
+			counts[n].Count++
+			if instance.Status == types.InstanceStateNameRunning {
+				counts[n].Running++
+			}
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	return counts, nil
+}
+
+// WriteGroupCounts writes the instance counts grouped by a dimension to w in
+// tabular format
+func WriteGroupCounts(w io.Writer, dimension string, counts []GroupCount, opts TableOptions) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{dimension, "Count", "Running"})
+	if !opts.NoColor {
+		table.SetHeaderColor(
+			tablewriter.Colors{tablewriter.Bold},
+			tablewriter.Colors{tablewriter.Bold},
+			tablewriter.Colors{tablewriter.Bold},
+		)
+	}
+	for _, c := range counts {
+		value := c.Value
+		if value == "" {
+			value = "(none)"
+		}
+		table.Append([]string{value, strconv.Itoa(c.Count), strconv.Itoa(c.Running)})
+	}
+	table.Render()
+}
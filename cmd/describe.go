@@ -0,0 +1,105 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+	"github.com/frgrisk/ec2ctl/cmd/types"
+
+	"github.com/spf13/cobra"
+)
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe INSTANCE-ID",
+	Short: "Show the details of an instance",
+	Long: `This command finds the region of an instance and shows all of its details,
+	including its tags, networking, block devices, IAM profile, launch time and
+	state reason.
+
+	Examples:
+	# Describe an instance
+	ec2ctl describe i-04f95703166d053ed
+	# Describe an instance as JSON
+	ec2ctl describe i-04f95703166d053ed --output json
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		return validateInstanceID(args[0])
+	},
+	Run: describeInstance,
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+}
+
+func describeInstance(_ *cobra.Command, args []string) {
+	id := args[0]
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	accSum := getAccountSummary(ctx, regions, tags, "", []string{id}, nil)
+	region, err := aws.GetInstanceRegion(accSum, id)
+	if err != nil {
+		fmt.Println("instance", id, "could not be found")
+		os.Exit(1)
+	}
+
+	inst, err := aws.DescribeInstance(ctx, creds, region, id)
+	if err != nil {
+		fmt.Printf("error describing instance %s: %v\n", id, err)
+		os.Exit(1)
+	}
+
+	f, closeOutput, err := openOutput()
+	if err != nil {
+		fmt.Println("cannot open output file:", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := closeOutput(); err != nil {
+			fmt.Println("cannot write output file:", err)
+		}
+	}()
+
+	if output == types.JSON {
+		jsonBytes, err := json.MarshalIndent(inst, "", "  ")
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Fprintln(f, string(jsonBytes))
+		return
+	}
+	if err := aws.WriteDetails(f, region, inst); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
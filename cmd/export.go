@@ -0,0 +1,144 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a snapshot of the instance inventory to a directory",
+	Long: `This command queries the matching instances in every region and writes
+	them to instances.json, instances.csv and instances.yaml in a directory,
+	together with the list of regions they are in in regions.txt, for ingestion
+	by other tools.
+
+	Examples:
+	# Export every instance in the account
+	ec2ctl export --dir ./out
+	# Export the instances launched in the last week
+	ec2ctl export --dir ./out --since 7d
+	`,
+	Run: exportInstances,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String("dir", "", "Directory to write the export to, which is created if it doesn't exist")
+	_ = exportCmd.MarkFlagRequired("dir")
+	exportCmd.Flags().String("since", "", "Only export instances launched after an RFC3339 time or a time ago given in hours, days or weeks (e.g. 2024-01-02T15:04:05Z or 30d)")
+}
+
+func exportInstances(cmd *cobra.Command, _ []string) {
+	dir, err := cmd.Flags().GetString("dir")
+	if err != nil {
+		fmt.Println("cannot get value of dir flag:", err)
+		return
+	}
+	sinceText, err := cmd.Flags().GetString("since")
+	if err != nil {
+		fmt.Println("cannot get value of since flag:", err)
+		return
+	}
+	since, err := parseTimeBound(sinceText)
+	if err != nil {
+		fmt.Println("invalid --since:", err)
+		os.Exit(1)
+	}
+
+	// A snapshot covers the whole account unless regions are given
+	allRegions = true
+
+	ctx, cancel := newContext()
+	defer cancel()
+	accSum := getAccountSummary(ctx, regions, tags, "", nil, nil)
+	if !since.IsZero() {
+		accSum = filterInstances(accSum, func(i aws.Instance) bool {
+			return launchedWithin(i, since, time.Time{})
+		})
+	}
+
+	if err := writeExport(dir, accSum); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d instances in %d regions to %s\n", accSum.Stats().Total, len(accSum), dir)
+}
+
+// writeExport writes the instances of the account summary to dir in JSON, CSV
+// and YAML, along with the names of their regions
+func writeExport(dir string, accSum aws.AccountSummary) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Always write a list rather than null, so consumers needn't special-case
+	// an empty account
+	if accSum == nil {
+		accSum = aws.AccountSummary{}
+	}
+	jsonBytes, err := json.MarshalIndent(accSum, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "instances.json"), append(jsonBytes, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	yamlBytes, err := yaml.Marshal(accSum)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "instances.yaml"), yamlBytes, 0o644); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, "instances.csv"))
+	if err != nil {
+		return err
+	}
+	if err := accSum.WriteCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	var regionNames []byte
+	for _, regionSum := range accSum {
+		regionNames = append(regionNames, regionSum.Region+"\n"...)
+	}
+	return os.WriteFile(filepath.Join(dir, "regions.txt"), regionNames, 0o644)
+}
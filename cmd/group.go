@@ -0,0 +1,79 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// group is the name of the instance group selected with --group
+var group string
+
+// groupSelector is the selector of an instance group defined in the config
+// file. Tags are given as key=value strings, since viper lowercases map keys
+// and tag keys are case-sensitive, e.g.:
+//
+//	groups:
+//	  dev-cluster:
+//	    regions: [us-east-1, us-west-2]
+//	    tags: [Environment=dev, Role=web]
+type groupSelector struct {
+	Regions []string
+	Tags    []string
+}
+
+// applyGroup loads the named group's selector from the config file and merges
+// it into the regions and tags filters. Regions given on the command line
+// replace the group's regions, and tags given on the command line take
+// precedence over the group's tags with the same key.
+func applyGroup(name string) error {
+	key := "groups." + name
+	if !viper.IsSet(key) {
+		return fmt.Errorf("group %q is not defined in the config file", name)
+	}
+	var sel groupSelector
+	if err := viper.UnmarshalKey(key, &sel); err != nil {
+		return fmt.Errorf("invalid definition of group %q: %w", name, err)
+	}
+
+	if len(regions) == 0 {
+		regions = sel.Regions
+	}
+	groupTags := make(map[string][]string, len(sel.Tags))
+	for _, tag := range sel.Tags {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok {
+			return fmt.Errorf("invalid tag %q in group %q: must be key=value", tag, name)
+		}
+		groupTags[k] = append(groupTags[k], v)
+	}
+	for k, v := range groupTags {
+		if _, set := tags[k]; !set {
+			tags[k] = v
+		}
+	}
+	return nil
+}
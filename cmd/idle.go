@@ -0,0 +1,175 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+)
+
+// idleCmd represents the idle command
+var idleCmd = &cobra.Command{
+	Use:   "idle",
+	Short: "Show the costs stopped instances still incur",
+	Long: `This command lists the stopped instances along with the EBS volumes and
+	Elastic IPs they are still billed for, and the Elastic IPs in their regions
+	that aren't associated with anything, which can optionally be released.
+
+	Unattached Elastic IPs are only looked up in the regions that have stopped
+	instances. Instance filters such as --tag don't apply to them, so
+	--release-unattached can't be combined with those filters.
+
+	Examples:
+	# Show the stopped instances in the default region
+	ec2ctl idle
+	# Show the stopped instances in all regions
+	ec2ctl idle --all-regions
+	# Release the unattached Elastic IPs, after confirmation
+	ec2ctl idle --release-unattached
+	`,
+	Run: idleInstances,
+}
+
+func init() {
+	rootCmd.AddCommand(idleCmd)
+
+	idleCmd.Flags().Bool("release-unattached", false, "Release the Elastic IPs that aren't associated with anything in the regions with stopped instances, after confirmation; can't be combined with instance filters")
+	idleCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check permissions and report what would be released without releasing the Elastic IPs")
+}
+
+func idleInstances(cmd *cobra.Command, args []string) {
+	release, err := cmd.Flags().GetBool("release-unattached")
+	if err != nil {
+		fmt.Println("cannot get value of release-unattached flag:", err)
+		return
+	}
+	// Filters select instances rather than Elastic IPs, so a filtered run
+	// would still release addresses that have nothing to do with it
+	if release && hasInstanceFilters(args) {
+		fmt.Println("Error: --release-unattached releases every unattached Elastic IP in the queried regions, so it can't be combined with instance IDs or filters such as --tag")
+		os.Exit(1)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	// Get account summary based on regions and tags specified
+	accSum := getAccountSummary(ctx, regions, tags, aws.InstanceStatus, args, []ec2types.InstanceStateName{ec2types.InstanceStateNameStopped})
+	if len(accSum) == 0 {
+		fmt.Println("No stopped instances were found.")
+		return
+	}
+
+	// Look up the Elastic IPs of each region with stopped instances at once
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	addresses := make(map[string][]aws.Address, len(accSum))
+	for _, regionSum := range accSum {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+			found, err := aws.Addresses(ctx, creds, region)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to query the Elastic IPs in region %s: %v\n", region, err)
+				return
+			}
+			mu.Lock()
+			addresses[region] = found
+			mu.Unlock()
+		}(regionSum.Region)
+	}
+	wg.Wait()
+
+	var idle []aws.IdleInstance
+	var unattached []aws.Address
+	for _, regionSum := range accSum {
+		byInstance := make(map[string][]aws.Address)
+		for _, a := range addresses[regionSum.Region] {
+			if a.Unattached() {
+				unattached = append(unattached, a)
+				continue
+			}
+			// Addresses associated with other network interfaces, such as
+			// those of NAT gateways, are in use and left out
+			if a.InstanceID != "" {
+				byInstance[a.InstanceID] = append(byInstance[a.InstanceID], a)
+			}
+		}
+		for _, i := range regionSum.Instances {
+			idle = append(idle, aws.IdleInstance{Instance: i, Addresses: byInstance[i.ID]})
+		}
+	}
+
+	if err := aws.WriteIdle(os.Stdout, idle, unattached); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println()
+	fmt.Println(aws.IdleSummary(idle, unattached))
+
+	if release && len(unattached) > 0 {
+		releaseAddresses(unattached)
+	}
+}
+
+// releaseAddresses releases the Elastic IP addresses once the user confirms
+func releaseAddresses(addresses []aws.Address) {
+	if !dryRun && !assumeYes {
+		fmt.Printf(`
+Are you sure you want to release the %d unattached Elastic IPs above?
+	Only 'yes' will be accepted to approve
+
+	Enter a value: `, len(addresses))
+		reader := bufio.NewReader(os.Stdin)
+		text, _ := reader.ReadString('\n')
+		if strings.TrimSpace(text) != "yes" {
+			return
+		}
+	}
+
+	failed := 0
+	for _, a := range addresses {
+		ctx, cancel := newContext()
+		err := aws.ReleaseAddress(ctx, creds, a.Region, a.AllocationID, dryRun)
+		cancel()
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("%s: error releasing Elastic IP %s: %s\n", a.Region, a.PublicIP, err)
+		case dryRun:
+			fmt.Printf("%s: would release Elastic IP %s\n", a.Region, a.PublicIP)
+		default:
+			fmt.Printf("%s: released Elastic IP %s\n", a.Region, a.PublicIP)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
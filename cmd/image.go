@@ -0,0 +1,131 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	"github.com/spf13/cobra"
+)
+
+// imageCmd represents the image command
+var imageCmd = &cobra.Command{
+	Use:     "image",
+	Short:   "Manage instance images (AMIs)",
+	Long:    `This command manages images (AMIs) created from instances.`,
+	Aliases: []string{"snapshot"},
+}
+
+// imageCreateCmd represents the image create command
+var imageCreateCmd = &cobra.Command{
+	Use:   "create INSTANCE-ID [INSTANCE-ID...]",
+	Short: "Create an image (AMI) from one or more instances",
+	Long: `This command creates an image (AMI) from each of the specified instance(s)
+	and prints the resulting AMI IDs.
+
+	The image name is rendered from --name-template, which has access to the
+	instance's {{.Name}} tag, {{.ID}}, and a {{.Timestamp}} of the form
+	20060102-150405.`,
+	Args: func(_ *cobra.Command, args []string) error {
+		return validateInstanceArgs(args)
+	},
+	Example: "ec2ctl image create --no-reboot --name-template '{{.Name}}-backup-{{.Timestamp}}' i-04f95703166d053ed",
+	Run:     createImages,
+}
+
+// imageNameData is the data available to the image name template
+type imageNameData struct {
+	Name      string
+	ID        string
+	Timestamp string
+}
+
+func init() {
+	rootCmd.AddCommand(imageCmd)
+	imageCmd.AddCommand(imageCreateCmd)
+
+	imageCreateCmd.Flags().Bool("no-reboot", false, "Do not reboot the instance before creating the image (image consistency is not guaranteed)")
+	imageCreateCmd.Flags().String("name-template", "{{.Name}}-{{.Timestamp}}", "Go template for the image name")
+}
+
+func createImages(cmd *cobra.Command, instances []string) {
+	noReboot, err := cmd.Flags().GetBool("no-reboot")
+	if err != nil {
+		fmt.Println("cannot get value of no-reboot flag:", err)
+		return
+	}
+	nameTemplate, err := cmd.Flags().GetString("name-template")
+	if err != nil {
+		fmt.Println("cannot get value of name-template flag:", err)
+		return
+	}
+	tmpl, err := template.New("name").Parse(nameTemplate)
+	if err != nil {
+		fmt.Println("invalid name template:", err)
+		return
+	}
+
+	// Creating an image reboots the instance by default, so refuse to do it
+	// to a whole region
+	if !hasInstanceFilters(instances) {
+		fmt.Println("Error: give instance IDs or names, or a filter such as --tag; --regions alone would image every instance in the region")
+		os.Exit(1)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	// Get account summary based on regions and tags specified
+	accSum := getAccountSummary(ctx, regions, tags, "", instances, nil)
+
+	timestamp := time.Now().UTC().Format("20060102-150405")
+	for _, r := range accSum {
+		for _, i := range r.Instances {
+			data := imageNameData{
+				Name:      i.Name,
+				ID:        i.ID,
+				Timestamp: timestamp,
+			}
+			if data.Name == "" {
+				data.Name = i.ID
+			}
+			var name strings.Builder
+			if err := tmpl.Execute(&name, data); err != nil {
+				fmt.Printf("error rendering image name for instance %s: %v\n", i.ID, err)
+				continue
+			}
+			imageID, err := aws.CreateImage(ctx, creds, i.Region, i.ID, name.String(), noReboot)
+			if err != nil {
+				fmt.Printf("error creating image of instance %s: %v\n", i.ID, err)
+				continue
+			}
+			fmt.Printf("%s\t%s\t%s\n", i.ID, imageID, name.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+// exitInterrupted is the conventional exit code of a command stopped by SIGINT
+const exitInterrupted = 130
+
+// notifyInterrupt returns a context that is cancelled when the user presses
+// Ctrl-C, so in-flight AWS calls are abandoned and the command can report what
+// it did. A second Ctrl-C exits immediately.
+func notifyInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// interrupted reports whether ctx was cancelled by notifyInterrupt, as
+// opposed to timing out
+func interrupted(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// opTracker records the operations a command has issued and which of them
+// have completed, so that an interrupted command can report both
+type opTracker struct {
+	mu      sync.Mutex
+	pending []string
+	done    []string
+}
+
+// start records that the described operation was issued
+func (t *opTracker) start(op string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, op)
+}
+
+// finish records that the described operation completed, successfully or not
+func (t *opTracker) finish(op string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i := slices.Index(t.pending, op); i >= 0 {
+		t.pending = slices.Delete(t.pending, i, i+1)
+	}
+	t.done = append(t.done, op)
+}
+
+// report writes the completed operations and those still in flight to w
+func (t *opTracker) report(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintln(w, "Interrupted.")
+	for _, op := range t.done {
+		fmt.Fprintln(w, "  completed:", op)
+	}
+	for _, op := range t.pending {
+		fmt.Fprintln(w, "  in flight (may or may not have been applied):", op)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// outcomeTerminated is the journal outcome of an instance that was terminated
+const outcomeTerminated = "terminated"
+
+// outcomeProtected is the journal outcome of an instance that was skipped
+// because its termination protection is enabled
+const outcomeProtected = "skipped: termination protection enabled"
+
+// journalEntry is the outcome of terminating an instance
+type journalEntry struct {
+	Region  string
+	Outcome string
+}
+
+// terminateJournal records the outcome of terminating each instance in a file,
+// so that a bulk terminate that was interrupted can be resumed
+type terminateJournal struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]journalEntry
+}
+
+// loadJournal reads the journal at path. A missing file is an empty journal,
+// so the same path can be used to start and to resume a terminate.
+func loadJournal(path string) (*terminateJournal, error) {
+	j := &terminateJournal{path: path, entries: make(map[string]journalEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &j.entries); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// terminated reports whether the journal records the instance as terminated
+func (j *terminateJournal) terminated(id string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.entries[id].Outcome == outcomeTerminated
+}
+
+// record sets the outcome of terminating the instances in a region and
+// writes the journal. The file is replaced atomically, so a crash while
+// writing leaves the previous journal intact.
+func (j *terminateJournal) record(region string, ids []string, err error) error {
+	outcome := outcomeTerminated
+	if err != nil {
+		outcome = "failed: " + opErrorCause(err).Error()
+	}
+	return j.recordOutcome(region, ids, outcome)
+}
+
+// recordOutcome sets the outcome of the instances in a region and writes the
+// journal
+func (j *terminateJournal) recordOutcome(region string, ids []string, outcome string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, id := range ids {
+		j.entries[id] = journalEntry{Region: region, Outcome: outcome}
+	}
+
+	data, err := json.MarshalIndent(j.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the IDs of matching instances",
+	Long: `This command prints the IDs of all matching instances, one per line, so
+	they can be piped into other commands.
+
+	Examples:
+	# List all instances in the default region
+	ec2ctl list
+	# List all instances in all regions
+	ec2ctl list --all-regions
+	# List instances with a specific tag, prefixed with their region
+	ec2ctl list --tag Environment=dev --region-prefix
+	# Stop the listed instances
+	ec2ctl list --tag Environment=dev | xargs ec2ctl stop
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		regionPrefix, err := cmd.Flags().GetBool("region-prefix")
+		if err != nil {
+			fmt.Println("cannot get value of region-prefix flag:", err)
+			return
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		// Get account summary based on regions and tags specified
+		accSum := getAccountSummary(ctx, regions, tags, aws.InstanceStatus, args, nil)
+
+		f, closeOutput, err := openOutput()
+		if err != nil {
+			fmt.Println("cannot open output file:", err)
+			return
+		}
+		defer func() {
+			if err := closeOutput(); err != nil {
+				fmt.Println("cannot write output file:", err)
+			}
+		}()
+
+		for _, regionSum := range accSum {
+			for _, id := range aws.IDs(regionSum.Instances) {
+				if regionPrefix {
+					fmt.Fprintf(f, "%s/%s\n", regionSum.Region, id)
+				} else {
+					fmt.Fprintln(f, id)
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().Bool("region-prefix", false, "Prefix each instance ID with its region (e.g. us-east-1/i-0abc...)")
+}
@@ -23,10 +23,17 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/frgrisk/ec2ctl/adapter/aws"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/spf13/cobra"
 )
 
@@ -38,8 +45,84 @@ var modifyCmd = &cobra.Command{
 	Args: func(_ *cobra.Command, args []string) error {
 		return validateInstanceArgs(args)
 	},
-	Example: "ec2ctl modify --type r6g.xlarge i-04f95703166d053ed",
-	Run:     modifyInstances,
+	Example: `ec2ctl modify --type r6g.xlarge i-04f95703166d053ed
+ec2ctl modify --shutdown-behavior terminate --disable-api-termination=false i-04f95703166d053ed`,
+	PreRunE: func(cmd *cobra.Command, _ []string) error {
+		if !cmd.Flags().Changed("type") && !cmd.Flags().Changed("ebs-optimized") &&
+			!cmd.Flags().Changed("shutdown-behavior") && !cmd.Flags().Changed("disable-api-termination") {
+			return errors.New("at least one of --type, --ebs-optimized, --shutdown-behavior, or --disable-api-termination is required")
+		}
+		t, err := cmd.Flags().GetString("type")
+		if err != nil {
+			return err
+		}
+		if t != "" {
+			if err := validateInstanceType(t); err != nil {
+				return err
+			}
+		}
+		behavior, err := cmd.Flags().GetString("shutdown-behavior")
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("shutdown-behavior") && !slices.Contains(ec2types.ShutdownBehavior("").Values(), ec2types.ShutdownBehavior(behavior)) {
+			return fmt.Errorf("invalid shutdown behavior %q: must be stop or terminate", behavior)
+		}
+		return nil
+	},
+	Run: modifyInstances,
+}
+
+// validateInstanceType checks that t is a known instance type, suggesting the
+// closest known type on a near-miss
+func validateInstanceType(t string) error {
+	known := ec2types.InstanceType("").Values()
+	if slices.Contains(known, ec2types.InstanceType(t)) {
+		return nil
+	}
+	candidates := make([]string, 0, len(known))
+	for _, k := range known {
+		candidates = append(candidates, string(k))
+	}
+	if suggestion := closestMatch(t, candidates); suggestion != "" {
+		return fmt.Errorf("%q is not a known instance type, did you mean %q?", t, suggestion)
+	}
+	return fmt.Errorf("%q is not a known instance type", t)
+}
+
+// closestMatch returns the candidate with the smallest edit distance to s, or
+// an empty string if none are close enough to be a likely typo
+func closestMatch(s string, candidates []string) string {
+	// Allow roughly one edit for every four characters
+	maxDistance := len(s)/4 + 1
+	best, bestDistance := "", maxDistance+1
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(s), strings.ToLower(c)); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
 }
 
 func init() {
@@ -54,43 +137,215 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	modifyCmd.Flags().String("type", "", "Instance type to change the instance(s) to.")
-	_ = modifyCmd.MarkFlagRequired("type")
+	modifyCmd.Flags().Bool("ebs-optimized", false, "Enable or disable EBS optimization (requires the instances to be stopped)")
+	modifyCmd.Flags().String("shutdown-behavior", "", "Whether the instances stop or terminate when shut down from within (stop or terminate)")
+	modifyCmd.Flags().Bool("disable-api-termination", false, "Enable or disable the termination protection of the instances")
+	modifyCmd.Flags().Bool("stop-start", false, "Stop running instances before modifying them and start them again afterwards")
+	modifyCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 10*time.Minute, "Maximum time to wait for the instances to stop or start when --stop-start is set")
+	modifyCmd.Flags().StringVar(&instanceIDFile, "instance-id-file", "", "File listing the IDs of instances to modify, one per line (blank lines and # comments are ignored)")
+	modifyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check permissions and report what would change without modifying the instances")
 }
 
 func modifyInstances(cmd *cobra.Command, instances []string) {
+	instances, _, err := expandInstanceArgs(instances)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
 	// Get account summary based on regions and tags specified
-	accSum := getAccountSummary(regions, tags, "", instances)
+	queryCtx, cancelQuery := newContext()
+	accSum := getAccountSummary(queryCtx, regions, tags, "", instances, nil)
+	cancelQuery()
 
-	instanceMap := make(map[string]*aws.Instance, 0)
+	ids, names := splitInstanceArgs(instances)
+	checkNameMatches(accSum, names)
+	reportUnmatchedNames(accSum, names)
+	instanceMap := selectInstances(accSum, ids, names)
 
-	for _, i := range instances {
-		instanceMap[i] = nil
+	t, err := cmd.Flags().GetString("type")
+	if err != nil {
+		fmt.Println("error parsing type flag:", err)
+		return
 	}
 
-	for _, r := range accSum {
-		for n, i := range r.Instances {
-			_, ok := instanceMap[i.ID]
-			if ok {
-				instanceMap[i.ID] = &r.Instances[n]
-			}
-		}
+	stopStart, err := cmd.Flags().GetBool("stop-start")
+	if err != nil {
+		fmt.Println("error parsing stop-start flag:", err)
+		return
 	}
 
-	t, err := cmd.Flags().GetString("type")
+	attrs, err := instanceAttributes(cmd)
 	if err != nil {
-		fmt.Println("error parsing type flag:", err)
+		fmt.Println(err)
 		return
 	}
+	changes := describeChanges(t, attrs)
+	// The instance type and EBS optimization can only be changed while the
+	// instance is stopped
+	needsStop := t != "" || attrs.EBSOptimized != nil
 
+	failed := 0
 	for k, v := range instanceMap {
 		if v == nil {
 			fmt.Printf("instance %s not found\n", k)
 			continue
 		}
-		err := aws.ModifyInstanceType(v.Region, t, k)
+
+		restart := false
+		if needsStop && v.Status != ec2types.InstanceStateNameStopped {
+			if !stopStart || v.Status != ec2types.InstanceStateNameRunning {
+				fmt.Printf("instance %s must be stopped to modify it (current state: %s)\n", k, v.Status)
+				continue
+			}
+			if dryRun {
+				fmt.Printf("would stop instance %s, modify its %s, and start it again\n", k, changes)
+				continue
+			}
+			fmt.Printf("stopping instance %s...\n", k)
+			if err := transitionInstance(v.Region, aws.InstanceStop, k); err != nil {
+				fmt.Printf("error stopping instance %s: %v\n", k, opErrorCause(err))
+				failed++
+				continue
+			}
+			restart = true
+		}
+
+		// An instance stopped for the modification is started again even if
+		// the modification failed, rather than being left stopped
+		modifyErr := modifyInstance(v, t, attrs)
+		if modifyErr != nil {
+			failed++
+		}
+		if restart {
+			fmt.Printf("starting instance %s...\n", k)
+			if err := transitionInstance(v.Region, aws.InstanceStart, k); err != nil {
+				fmt.Printf("error starting instance %s: %v; it was left stopped\n", k, opErrorCause(err))
+				if modifyErr == nil {
+					failed++
+				}
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d instances failed to be modified.\n", failed)
+		os.Exit(1)
+	}
+}
+
+// selectInstances maps the IDs of the instances selected by ID or name to
+// the instances in the account summary. Instances selected by ID that
+// weren't found map to nil. The instances are referenced by their index in
+// the summary, so each keeps its own region.
+func selectInstances(accSum aws.AccountSummary, ids []string, names []string) map[string]*aws.Instance {
+	instanceMap := make(map[string]*aws.Instance, len(ids))
+	// Instances selected by name are only known once they are found
+	for _, i := range ids {
+		instanceMap[i] = nil
+	}
+	for _, r := range accSum {
+		for n, i := range r.Instances {
+			_, ok := instanceMap[i.ID]
+			if ok || matchesName(i, names) {
+				instanceMap[i.ID] = &r.Instances[n]
+			}
+		}
+	}
+	return instanceMap
+}
+
+// modifyInstance changes the type and attributes of an instance, printing
+// the outcome. The returned error has already been printed.
+func modifyInstance(v *aws.Instance, t string, attrs aws.InstanceAttributes) error {
+	if t != "" {
+		ctx, cancel := newContext()
+		err := aws.ModifyInstanceType(ctx, creds, v.Region, t, v.ID, dryRun)
+		cancel()
+		if err != nil {
+			fmt.Printf("error modifying instance %s: %v\n", v.ID, opErrorCause(err))
+			return err
+		}
+		if dryRun {
+			fmt.Printf("would modify instance %s from type %s to %s\n", v.ID, v.Type, t)
+		} else {
+			fmt.Printf("modified instance %s from type %s to %s\n", v.ID, v.Type, t)
+		}
+	}
+
+	if attrs != (aws.InstanceAttributes{}) {
+		ctx, cancel := newContext()
+		err := aws.ModifyInstanceAttributes(ctx, creds, v.Region, v.ID, attrs, dryRun)
+		cancel()
 		if err != nil {
-			fmt.Printf("error modifying instance %s: %v\n", k, err)
-			return
+			fmt.Printf("error modifying instance %s: %v\n", v.ID, opErrorCause(err))
+			return err
 		}
+		if dryRun {
+			fmt.Printf("would modify the %s of instance %s\n", describeChanges("", attrs), v.ID)
+		} else {
+			fmt.Printf("modified the %s of instance %s\n", describeChanges("", attrs), v.ID)
+		}
+	}
+	return nil
+}
+
+// instanceAttributes returns the attributes to modify, leaving out those
+// whose flags weren't given so they are left unchanged
+func instanceAttributes(cmd *cobra.Command) (aws.InstanceAttributes, error) {
+	var attrs aws.InstanceAttributes
+	if cmd.Flags().Changed("ebs-optimized") {
+		v, err := cmd.Flags().GetBool("ebs-optimized")
+		if err != nil {
+			return attrs, fmt.Errorf("error parsing ebs-optimized flag: %w", err)
+		}
+		attrs.EBSOptimized = &v
+	}
+	if cmd.Flags().Changed("shutdown-behavior") {
+		v, err := cmd.Flags().GetString("shutdown-behavior")
+		if err != nil {
+			return attrs, fmt.Errorf("error parsing shutdown-behavior flag: %w", err)
+		}
+		attrs.ShutdownBehavior = &v
+	}
+	if cmd.Flags().Changed("disable-api-termination") {
+		v, err := cmd.Flags().GetBool("disable-api-termination")
+		if err != nil {
+			return attrs, fmt.Errorf("error parsing disable-api-termination flag: %w", err)
+		}
+		attrs.DisableAPITermination = &v
 	}
+	return attrs, nil
+}
+
+// describeChanges lists the modifications being made, e.g.
+// "type (to r6g.xlarge), shutdown behavior (to terminate)"
+func describeChanges(t string, attrs aws.InstanceAttributes) string {
+	var changes []string
+	if t != "" {
+		changes = append(changes, fmt.Sprintf("type (to %s)", t))
+	}
+	if attrs.EBSOptimized != nil {
+		changes = append(changes, fmt.Sprintf("EBS optimization (to %t)", *attrs.EBSOptimized))
+	}
+	if attrs.ShutdownBehavior != nil {
+		changes = append(changes, fmt.Sprintf("shutdown behavior (to %s)", *attrs.ShutdownBehavior))
+	}
+	if attrs.DisableAPITermination != nil {
+		changes = append(changes, fmt.Sprintf("termination protection (to %t)", *attrs.DisableAPITermination))
+	}
+	return strings.Join(changes, ", ")
+}
+
+// transitionInstance starts or stops an instance and waits until it reaches
+// the target state
+func transitionInstance(region string, action string, instanceID string) error {
+	ctx, cancel := newContext()
+	_, _, err := aws.StartStopInstance(ctx, creds, region, action, []string{instanceID}, false)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancelWait()
+	return aws.WaitForInstances(waitCtx, creds, region, action, []string{instanceID}, waitTimeout)
 }
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+)
+
+func TestSelectInstancesKeepsRegions(t *testing.T) {
+	accSum := aws.AccountSummary{
+		{Region: "eu-west-1", Instances: []aws.Instance{
+			{ID: "i-0000000000000001", Name: "web-1", Region: "eu-west-1"},
+		}},
+		{Region: "us-east-1", Instances: []aws.Instance{
+			{ID: "i-0000000000000002", Name: "web-2", Region: "us-east-1"},
+			{ID: "i-0000000000000003", Name: "db-1", Region: "us-east-1"},
+		}},
+	}
+
+	instanceMap := selectInstances(accSum, []string{"i-0000000000000001", "i-0000000000000009"}, []string{"web-2"})
+	want := map[string]string{
+		"i-0000000000000001": "eu-west-1",
+		"i-0000000000000002": "us-east-1",
+	}
+	for id, region := range want {
+		i := instanceMap[id]
+		if i == nil {
+			t.Errorf("instance %s was not selected", id)
+			continue
+		}
+		if i.ID != id || i.Region != region {
+			t.Errorf("instance %s maps to %s in %s, want it in %s", id, i.ID, i.Region, region)
+		}
+	}
+	if i, ok := instanceMap["i-0000000000000009"]; !ok || i != nil {
+		t.Error("an instance ID that wasn't found should map to nil")
+	}
+	if _, ok := instanceMap["i-0000000000000003"]; ok {
+		t.Error("an instance that wasn't selected is in the map")
+	}
+}
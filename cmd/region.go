@@ -0,0 +1,160 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+)
+
+var regionCacheTTL time.Duration
+
+var refreshRegions bool
+
+// regionCache is the list of regions available to an account, as cached on disk
+type regionCache struct {
+	FetchedAt time.Time
+	Regions   []string
+}
+
+// regionAliases maps common region shorthands to their region names
+var regionAliases = map[string]string{
+	"use1":  "us-east-1",
+	"use2":  "us-east-2",
+	"usw1":  "us-west-1",
+	"usw2":  "us-west-2",
+	"cac1":  "ca-central-1",
+	"sae1":  "sa-east-1",
+	"euw1":  "eu-west-1",
+	"euw2":  "eu-west-2",
+	"euw3":  "eu-west-3",
+	"euc1":  "eu-central-1",
+	"eun1":  "eu-north-1",
+	"eus1":  "eu-south-1",
+	"aps1":  "ap-south-1",
+	"apse1": "ap-southeast-1",
+	"apse2": "ap-southeast-2",
+	"apne1": "ap-northeast-1",
+	"apne2": "ap-northeast-2",
+	"apne3": "ap-northeast-3",
+	"ape1":  "ap-east-1",
+	"mes1":  "me-south-1",
+	"afs1":  "af-south-1",
+}
+
+// normalizeRegions trims and lowercases the region names, dropping empty
+// entries and duplicates, so that e.g. "us-east-1, US-WEST-2" works as meant
+func normalizeRegions(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
+
+// resolveRegions expands region aliases and checks each region against the
+// regions available to the account, suggesting the closest match for typos
+func resolveRegions(names []string, available []string) ([]string, error) {
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		region := strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := regionAliases[region]; ok {
+			region = alias
+		}
+		if !slices.Contains(available, region) {
+			if suggestion := closestMatch(region, available); suggestion != "" {
+				return nil, fmt.Errorf("%q is not an available region, did you mean %q?", name, suggestion)
+			}
+			return nil, fmt.Errorf("%q is not an available region", name)
+		}
+		if !slices.Contains(resolved, region) {
+			resolved = append(resolved, region)
+		}
+	}
+	return resolved, nil
+}
+
+// getRegions returns the regions available to the account of the credentials.
+// The list is cached under the user's cache directory for --region-cache-ttl,
+// unless --refresh-regions is set. Failing to read or write the cache isn't
+// an error, since the regions can always be fetched.
+func getRegions(ctx context.Context, creds aws.Credentials) ([]string, error) {
+	path, err := regionCachePath(ctx, creds)
+	if err != nil {
+		logger.Debug("cannot locate the region cache", "error", err)
+	}
+	if path != "" && regionCacheTTL > 0 && !refreshRegions {
+		if data, err := os.ReadFile(path); err == nil {
+			var cache regionCache
+			if err := json.Unmarshal(data, &cache); err == nil && time.Since(cache.FetchedAt) < regionCacheTTL {
+				logger.Debug("using cached regions", "path", path, "fetchedAt", cache.FetchedAt)
+				return cache.Regions, nil
+			}
+		}
+	}
+
+	regions, err := aws.GetRegions(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" && regionCacheTTL > 0 {
+		data, err := json.Marshal(regionCache{FetchedAt: time.Now(), Regions: regions})
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		if err != nil {
+			logger.Debug("cannot write the region cache", "path", path, "error", err)
+		}
+	}
+	return regions, nil
+}
+
+// regionCachePath returns the path of the region cache for the credentials.
+// Regions are opted into per account, so the cache is keyed on the account
+// the credentials resolve to, however they were given.
+func regionCachePath(ctx context.Context, creds aws.Credentials) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	accountID, err := lookupAccountID(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ec2ctl", "regions-"+accountID+".json"), nil
+}
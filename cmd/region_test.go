@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeRegions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{
+			name:  "whitespace",
+			input: []string{" us-east-1", "us-west-2 ", "\teu-west-1\n"},
+			want:  []string{"us-east-1", "us-west-2", "eu-west-1"},
+		},
+		{
+			name:  "mixed case",
+			input: []string{"US-EAST-1", "Eu-West-1"},
+			want:  []string{"us-east-1", "eu-west-1"},
+		},
+		{
+			name:  "duplicates",
+			input: []string{"us-east-1", " US-East-1 ", "us-west-2", "us-east-1"},
+			want:  []string{"us-east-1", "us-west-2"},
+		},
+		{
+			name:  "empty entries",
+			input: []string{"", "us-east-1", "  ", "us-west-2", ""},
+			want:  []string{"us-east-1", "us-west-2"},
+		},
+		{
+			name:  "nothing left",
+			input: []string{" ", ""},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeRegions(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeRegions(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -23,12 +23,23 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/frgrisk/ec2ctl/adapter/aws"
 	"github.com/frgrisk/ec2ctl/cmd/types"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -36,15 +47,197 @@ var cfgFile string
 
 var regions []string
 
+var allRegions bool
+
+var creds aws.Credentials
+
 var output types.Output
 
-var tags map[string]string
+var timeout time.Duration
+
+var maxConcurrency int
+
+var noColor bool
+
+var statusStyle string
+
+// columns holds the --columns instance fields, resolved to their field names
+var columns []string
+
+var verbose bool
+
+var outputFile string
+
+var templateText string
+
+// outputTemplate is the parsed --template, used by the template output format
+var outputTemplate *template.Template
+
+var assumeYes bool
+
+// logger is the logger for diagnostic output, configured by initLogger
+var logger = slog.Default()
+
+var tagPairs []string
+
+// tags holds the parsed --tag values, mapping each tag key to the values it
+// may have
+var tags map[string][]string
+
+var nameRegex string
+
+var excludeTags []string
+
+var filterTypes []string
+
+var zones []string
+
+var vpcIDs []string
+
+var subnetIDs []string
+
+var tagKeys []string
+
+var amiIDs []string
+
+var launchTemplateIDs []string
+
+var iamProfiles []string
+
+var platform string
+
+// vpcIDRe and subnetIDRe match the IDs of VPCs and subnets, which have 8 or 17
+// hexadecimal characters after their prefix
+var vpcIDRe = regexp.MustCompile(`^vpc-([0-9a-f]{8}|[0-9a-f]{17})$`)
+var subnetIDRe = regexp.MustCompile(`^subnet-([0-9a-f]{8}|[0-9a-f]{17})$`)
+
+// amiIDRe and launchTemplateIDRe match the IDs of AMIs and launch templates,
+// which have 8 or 17 hexadecimal characters after their prefix
+var amiIDRe = regexp.MustCompile(`^ami-([0-9a-f]{8}|[0-9a-f]{17})$`)
+var launchTemplateIDRe = regexp.MustCompile(`^lt-([0-9a-f]{8}|[0-9a-f]{17})$`)
+
+// zoneRegionRe matches the name of an availability zone, capturing the name
+// of its region
+var zoneRegionRe = regexp.MustCompile(`^([a-z]{2}(?:-[a-z]+)+-\d+)[a-z]$`)
+
+// exclusions holds the parsed --exclude-tag values, mapping each tag key to
+// the values that exclude an instance
+var exclusions map[string][]string
+
+var nameRe *regexp.Regexp
+
+var createdAfterText string
+
+var createdBeforeText string
+
+// createdAfter and createdBefore are the parsed --created-after and
+// --created-before bounds on the launch time, zero when unset
+var createdAfter, createdBefore time.Time
+
+// relativeTimeRe matches a relative time such as 7d, capturing its count and
+// unit
+var relativeTimeRe = regexp.MustCompile(`^(\d+)([a-zA-Z]*)$`)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "ec2ctl",
 	Short: "ec2ctl is a command line tool for interacting with AWS EC2 instances",
-	Long:  `ec2ctl is a command line tool for interacting with AWS EC2 instances`,
+	Long: `ec2ctl is a command line tool for interacting with AWS EC2 instances
+
+Global flags can also be set with EC2CTL_ environment variables, named after
+the flag in upper case with dashes replaced by underscores (e.g.
+EC2CTL_REGIONS=us-east-1,us-west-2 or EC2CTL_OUTPUT=json), or as keys of the
+same name in the config file. Flags take precedence over environment
+variables, which take precedence over the config file.`,
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		initLogger()
+		aws.QueryConcurrency = maxConcurrency
+		var err error
+		if templateText != "" {
+			if outputTemplate, err = template.New("output").Parse(templateText); err != nil {
+				return fmt.Errorf("invalid template: %w", err)
+			}
+			output = types.Template
+		} else if output == types.Template {
+			return errors.New("the template output format requires --template")
+		}
+		if !slices.Contains(aws.StatusStyles(), aws.StatusStyle(statusStyle)) {
+			return fmt.Errorf("invalid status style %q: must be text, emoji or ascii", statusStyle)
+		}
+		if len(columns) > 0 {
+			if columns, err = aws.ColumnNames(columns); err != nil {
+				return err
+			}
+		}
+		if nameRegex != "" {
+			nameRe, err = regexp.Compile(nameRegex)
+			if err != nil {
+				return fmt.Errorf("invalid name regex %q: %w", nameRegex, err)
+			}
+		}
+		if tags, err = parseTagPairs(tagPairs); err != nil {
+			return err
+		}
+		if exclusions, err = parseTagPairs(excludeTags); err != nil {
+			return err
+		}
+		if createdAfter, err = parseTimeBound(createdAfterText); err != nil {
+			return fmt.Errorf("invalid --created-after: %w", err)
+		}
+		if createdBefore, err = parseTimeBound(createdBeforeText); err != nil {
+			return fmt.Errorf("invalid --created-before: %w", err)
+		}
+		if !createdAfter.IsZero() && !createdBefore.IsZero() && !createdAfter.Before(createdBefore) {
+			return errors.New("--created-after must be earlier than --created-before")
+		}
+		if platform != "" && platform != aws.PlatformLinux && platform != aws.PlatformWindows {
+			return fmt.Errorf("invalid platform %q: must be windows or linux", platform)
+		}
+		if tagFile != "" {
+			if err := applyTagFile(tagFile); err != nil {
+				return err
+			}
+		}
+		for _, t := range filterTypes {
+			// Wildcards such as m5.* are passed through to EC2
+			if strings.ContainsAny(t, "*?") {
+				continue
+			}
+			if err := validateInstanceType(t); err != nil {
+				return err
+			}
+		}
+		for _, id := range vpcIDs {
+			if !vpcIDRe.MatchString(id) {
+				return fmt.Errorf("%q is not a valid VPC id", id)
+			}
+		}
+		for _, id := range subnetIDs {
+			if !subnetIDRe.MatchString(id) {
+				return fmt.Errorf("%q is not a valid subnet id", id)
+			}
+		}
+		for _, id := range amiIDs {
+			if !amiIDRe.MatchString(id) {
+				return fmt.Errorf("%q is not a valid AMI id", id)
+			}
+		}
+		for _, id := range launchTemplateIDs {
+			if !launchTemplateIDRe.MatchString(id) {
+				return fmt.Errorf("%q is not a valid launch template id", id)
+			}
+		}
+		if group != "" {
+			if err := applyGroup(group); err != nil {
+				return err
+			}
+		}
+		regions = normalizeRegions(regions)
+		if len(regions) == 0 {
+			regions = zoneRegions(zones)
+		}
+		return creds.Validate()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -57,9 +250,128 @@ func init() {
 	cobra.OnInitialize(initConfig)
 	// Global Flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.ec2ctl.yaml)")
-	rootCmd.PersistentFlags().StringSliceVar(&regions, "regions", []string{}, "comma-separated list of AWS regions to operate in (default is all regions)")
-	rootCmd.PersistentFlags().Var(&output, "output", "output format (table, json)")
-	rootCmd.PersistentFlags().StringToStringVar(&tags, "tag", map[string]string{}, "query by tags - specified as key=value pairs (e.g. Environment=dev,Name=dev.example.com)")
+	rootCmd.PersistentFlags().StringSliceVar(&regions, "regions", []string{}, "comma-separated list of AWS regions to operate in, which may be shorthands such as use1 for us-east-1 (default is the region from AWS_REGION, the profile, or AWS_DEFAULT_REGION, in that order; instance IDs are looked up in all regions)")
+	rootCmd.PersistentFlags().BoolVar(&allRegions, "all-regions", false, "operate in all available regions when --regions isn't set, instead of only the default region")
+	rootCmd.PersistentFlags().StringVar(&creds.Profile, "profile", "", "named AWS profile to use from the shared config (default is the default credential chain)")
+	rootCmd.PersistentFlags().StringVar(&creds.RoleARN, "role-arn", "", "ARN of an IAM role to assume for cross-account operations")
+	rootCmd.PersistentFlags().StringVar(&creds.ExternalID, "external-id", "", "external ID to use when assuming the role given by --role-arn")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "log diagnostic output, such as the API calls made, to stderr")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "automatically confirm prompts, for running non-interactively (e.g. in CI)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output (also set by the NO_COLOR environment variable)")
+	rootCmd.PersistentFlags().StringVar(&statusStyle, "status-style", string(aws.StatusStyleText), "how statuses are rendered in tables (text, emoji, ascii)")
+	rootCmd.PersistentFlags().StringSliceVar(&columns, "columns", []string{}, "comma-separated instance fields to show as table columns, in order (e.g. Name,ID,Type,Status); the default depends on --output")
+	rootCmd.PersistentFlags().Var(&output, "output", "output format (table, wide, json, ndjson, csv, template)")
+	rootCmd.PersistentFlags().StringVar(&templateText, "template", "", "Go template to render the output with, evaluated against the list of regions and their instances; implies --output template (e.g. '{{range .}}{{range .Instances}}{{.ID}}{{println}}{{end}}{{end}}')")
+	rootCmd.PersistentFlags().StringVar(&outputFile, "output-file", "", "write the rendered output to the given file instead of stdout")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "maximum time to wait for AWS API calls to complete")
+	rootCmd.PersistentFlags().DurationVar(&regionCacheTTL, "region-cache-ttl", 24*time.Hour, "how long to cache the list of available regions for (0 disables the cache)")
+	rootCmd.PersistentFlags().BoolVar(&refreshRegions, "refresh-regions", false, "fetch the list of available regions instead of using the cached list")
+	rootCmd.PersistentFlags().IntVar(&maxConcurrency, "max-concurrency", 8, "maximum number of regions to query at the same time")
+	rootCmd.PersistentFlags().StringSliceVar(&tagPairs, "tag", []string{}, "query by tags - specified as key=value or key:value pairs (e.g. Environment=dev,Name:dev.example.com); repeating a key matches any of its values, and values may contain * and ? wildcards (e.g. Name=web-*); keys containing colons must use key=value")
+	rootCmd.PersistentFlags().StringVar(&tagFile, "tag-file", "", "query by the tags in a YAML or JSON file mapping tag keys to values; --tag takes precedence for the same key")
+	rootCmd.PersistentFlags().StringSliceVar(&filterTypes, "filter-type", []string{}, "query by instance type, may be repeated or comma-separated to match any of the types; values may contain * and ? wildcards (e.g. m5.large,c5.*)")
+	rootCmd.PersistentFlags().StringSliceVar(&zones, "az", []string{}, "query by availability zone, may be repeated or comma-separated (e.g. us-east-1a); only the zones' regions are queried unless --regions is set")
+	rootCmd.PersistentFlags().StringSliceVar(&vpcIDs, "vpc", []string{}, "query by VPC ID, may be repeated or comma-separated (e.g. vpc-0abc1234)")
+	rootCmd.PersistentFlags().StringSliceVar(&subnetIDs, "subnet", []string{}, "query by subnet ID, may be repeated or comma-separated (e.g. subnet-0def5678)")
+	rootCmd.PersistentFlags().StringSliceVar(&iamProfiles, "iam-profile", []string{}, "query by IAM instance profile, given as an ARN or a profile name, may be repeated or comma-separated")
+	rootCmd.PersistentFlags().StringVar(&platform, "platform", "", "query by platform (windows or linux)")
+	rootCmd.PersistentFlags().StringSliceVar(&amiIDs, "ami", []string{}, "query by AMI ID, may be repeated or comma-separated (e.g. ami-0abc1234)")
+	rootCmd.PersistentFlags().StringSliceVar(&launchTemplateIDs, "launch-template", []string{}, "query by the ID of the launch template instances were launched from, may be repeated or comma-separated (e.g. lt-0abc1234)")
+	rootCmd.PersistentFlags().StringSliceVar(&tagKeys, "has-tag", []string{}, "query by the presence of a tag key, whatever its value; repeating the flag matches instances that have all of the keys (e.g. Owner)")
+	rootCmd.PersistentFlags().StringArrayVar(&excludeTags, "exclude-tag", []string{}, "exclude instances by tag - specified as key:value, may be repeated; an instance matching any exclusion is dropped, even if it matches --tag (e.g. Protected:true)")
+	rootCmd.PersistentFlags().StringVar(&createdAfterText, "created-after", "", "query instances launched after an RFC3339 time or a time ago given in hours, days or weeks (e.g. 2024-01-02T15:04:05Z or 7d)")
+	rootCmd.PersistentFlags().StringVar(&createdBeforeText, "created-before", "", "query instances launched before an RFC3339 time or a time ago given in hours, days or weeks (e.g. 2024-01-02T15:04:05Z or 12h)")
+	rootCmd.PersistentFlags().StringVar(&nameRegex, "name-regex", "", "query by a regular expression matched against the Name tag, for patterns wildcards can't express")
+}
+
+// parseTagPairs parses tags specified as key=value or key:value into a map of
+// keys to values. Pairs containing an equals sign are split on the first one,
+// so keys with colons must use that form (e.g. aws:cloudformation:stack-name=x).
+// Other pairs are split on their first colon, so values may contain colons
+// (e.g. Owner:arn:aws:iam::123:role/x).
+func parseTagPairs(pairs []string) (map[string][]string, error) {
+	parsed := make(map[string][]string, len(pairs))
+	for _, pair := range pairs {
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			i = strings.Index(pair, ":")
+		}
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid tag %q: must be specified as key=value or key:value", pair)
+		}
+		key, value := pair[:i], pair[i+1:]
+		parsed[key] = append(parsed[key], value)
+	}
+	return parsed, nil
+}
+
+// zoneRegions returns the regions of the given availability zones, or nil if
+// the region of any zone can't be determined from its name (e.g. local zones)
+// so that all regions are queried
+func zoneRegions(zones []string) []string {
+	var zoneRegions []string
+	for _, zone := range zones {
+		m := zoneRegionRe.FindStringSubmatch(zone)
+		if m == nil {
+			return nil
+		}
+		if !slices.Contains(zoneRegions, m[1]) {
+			zoneRegions = append(zoneRegions, m[1])
+		}
+	}
+	return zoneRegions
+}
+
+// initLogger routes the diagnostic output of ec2ctl to stderr, logging
+// debug messages only when --verbose is set
+func initLogger() {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	aws.Logger = logger
+}
+
+// newContext returns a context bounded by the --timeout flag for a single
+// round of AWS API calls
+func newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// isTerminal reports whether f is connected to a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// tableOptions returns the table rendering options for the selected output
+// format. Colors are disabled by --no-color, by the NO_COLOR environment
+// variable (see https://no-color.org), or when the table isn't written to a
+// terminal so logs and files stay readable.
+func tableOptions(f *os.File) aws.TableOptions {
+	return aws.TableOptions{
+		Wide:        output == types.Wide,
+		NoColor:     noColor || os.Getenv("NO_COLOR") != "" || !isTerminal(f),
+		StatusStyle: aws.StatusStyle(statusStyle),
+		Columns:     columns,
+	}
+}
+
+// openOutput returns the file rendered output is written to, which is the file
+// given by --output-file or stdout. The returned function closes the file.
+func openOutput() (*os.File, func() error, error) {
+	if outputFile == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -78,10 +390,103 @@ func initConfig() {
 		viper.SetConfigName(".ec2ctl")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	bindEnv()
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+
+	cobra.CheckErr(applyConfigFlags())
+}
+
+// bindEnv reads the settings from EC2CTL_ environment variables, with the
+// dashes of flag names replaced by underscores
+func bindEnv() {
+	viper.SetEnvPrefix("ec2ctl")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv() // read in environment variables that match
+}
+
+// applyConfigFlags sets the global flags that weren't given on the command
+// line from their EC2CTL_ environment variables (e.g. EC2CTL_REGIONS for
+// --regions) or the config file, in that order of precedence
+func applyConfigFlags() error {
+	var err error
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "config" {
+			return
+		}
+		if bindErr := viper.BindEnv(f.Name); bindErr != nil {
+			err = bindErr
+			return
+		}
+		if !viper.IsSet(f.Name) {
+			return
+		}
+		// The values are set on the flags directly, so that they aren't
+		// reported as changed on the command line
+		var setErr error
+		switch value := viper.Get(f.Name).(type) {
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			setErr = setFlagList(f, items)
+		case []string:
+			setErr = setFlagList(f, value)
+		default:
+			setErr = f.Value.Set(fmt.Sprint(value))
+		}
+		if setErr != nil {
+			err = fmt.Errorf("invalid value for %s from the environment or config file: %w", f.Name, setErr)
+		}
+	})
+	return err
+}
+
+// setFlagList sets a flag to a list from the config file. Each item of the
+// list is kept separate, so that repeatable flags such as --exclude-tag get
+// every item rather than one joined by commas.
+func setFlagList(f *pflag.Flag, items []string) error {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(items)
+	}
+	return f.Value.Set(strings.Join(items, ","))
+}
+
+// parseTimeBound parses an RFC3339 time, or a relative time such as 12h, 7d
+// or 2w counted back from now. Units that could be read more than one way,
+// such as m for minutes or months, are rejected. An empty string is the zero
+// time.
+func parseTimeBound(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	m := relativeTimeRe.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC3339 time nor a relative time such as 7d", s)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid relative time %q: %w", s, err)
+	}
+	var unit time.Duration
+	switch m[2] {
+	case "h":
+		unit = time.Hour
+	case "d":
+		unit = 24 * time.Hour
+	case "w":
+		unit = 7 * 24 * time.Hour
+	case "":
+		return time.Time{}, fmt.Errorf("relative time %q has no unit: use h, d or w (e.g. %sd)", s, m[1])
+	default:
+		return time.Time{}, fmt.Errorf("relative time %q has an unsupported or ambiguous unit %q: use h, d or w", s, m[2])
+	}
+	return time.Now().Add(-time.Duration(n) * unit), nil
 }
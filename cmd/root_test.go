@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/frgrisk/ec2ctl/cmd/types"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestParseTagPairs(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []string
+		want  map[string][]string
+	}{
+		{
+			name:  "no colons",
+			pairs: []string{"Environment=dev"},
+			want:  map[string][]string{"Environment": {"dev"}},
+		},
+		{
+			name:  "colon separator",
+			pairs: []string{"Environment:dev"},
+			want:  map[string][]string{"Environment": {"dev"}},
+		},
+		{
+			name:  "one colon in value",
+			pairs: []string{"Window=09:00"},
+			want:  map[string][]string{"Window": {"09:00"}},
+		},
+		{
+			name:  "many colons in value",
+			pairs: []string{"Owner=arn:aws:iam::123:role/x"},
+			want:  map[string][]string{"Owner": {"arn:aws:iam::123:role/x"}},
+		},
+		{
+			name:  "many colons in key",
+			pairs: []string{"aws:cloudformation:stack-name=foo"},
+			want:  map[string][]string{"aws:cloudformation:stack-name": {"foo"}},
+		},
+		{
+			name:  "one colon in value with colon separator",
+			pairs: []string{"Window:09:00"},
+			want:  map[string][]string{"Window": {"09:00"}},
+		},
+		{
+			name:  "many colons in value with colon separator",
+			pairs: []string{"Owner:arn:aws:iam::123:role/x"},
+			want:  map[string][]string{"Owner": {"arn:aws:iam::123:role/x"}},
+		},
+		{
+			name:  "repeated key",
+			pairs: []string{"Environment=dev", "Environment:test"},
+			want:  map[string][]string{"Environment": {"dev", "test"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTagPairs(tt.pairs)
+			if err != nil {
+				t.Fatalf("parseTagPairs(%q) returned error: %v", tt.pairs, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTagPairs(%q) = %v, want %v", tt.pairs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagPairsInvalid(t *testing.T) {
+	for _, pair := range []string{"Environment", "=dev", ":dev"} {
+		if _, err := parseTagPairs([]string{pair}); err == nil {
+			t.Errorf("parseTagPairs(%q) returned no error", pair)
+		}
+	}
+}
+
+// resetConfigFlags restores the global flags and viper settings once the test
+// completes
+func resetConfigFlags(t *testing.T) {
+	t.Helper()
+	viper.Reset()
+	bindEnv()
+	t.Cleanup(func() {
+		viper.Reset()
+		rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				_ = sv.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+	})
+}
+
+func TestApplyConfigFlagsFromEnv(t *testing.T) {
+	resetConfigFlags(t)
+	t.Setenv("EC2CTL_OUTPUT", "json")
+	t.Setenv("EC2CTL_REGIONS", "us-east-1,us-west-2")
+	t.Setenv("EC2CTL_MAX_CONCURRENCY", "3")
+
+	if err := applyConfigFlags(); err != nil {
+		t.Fatalf("applyConfigFlags returned error: %v", err)
+	}
+	if output != types.JSON {
+		t.Errorf("output = %v, want %v", output, types.JSON)
+	}
+	if want := []string{"us-east-1", "us-west-2"}; !reflect.DeepEqual(regions, want) {
+		t.Errorf("regions = %q, want %q", regions, want)
+	}
+	if maxConcurrency != 3 {
+		t.Errorf("maxConcurrency = %d, want 3", maxConcurrency)
+	}
+	if rootCmd.PersistentFlags().Changed("regions") {
+		t.Error("regions from the environment is reported as changed")
+	}
+}
+
+func TestApplyConfigFlagsPrecedence(t *testing.T) {
+	resetConfigFlags(t)
+	t.Setenv("EC2CTL_OUTPUT", "json")
+	viper.SetDefault("output", "csv")
+	viper.SetDefault("timeout", "5s")
+	if err := rootCmd.PersistentFlags().Set("output", "wide"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyConfigFlags(); err != nil {
+		t.Fatalf("applyConfigFlags returned error: %v", err)
+	}
+	// The flag wins over the environment, which wins over the config
+	if output != types.Wide {
+		t.Errorf("output = %v, want %v", output, types.Wide)
+	}
+	if got := timeout.String(); got != "5s" {
+		t.Errorf("timeout = %s, want 5s", got)
+	}
+}
+
+func TestApplyConfigFlagsConfigList(t *testing.T) {
+	resetConfigFlags(t)
+	viper.SetDefault("exclude-tag", []interface{}{"Protected:true", "Team=data,ops"})
+	viper.SetDefault("regions", []interface{}{"eu-west-1", "eu-central-1"})
+
+	if err := applyConfigFlags(); err != nil {
+		t.Fatalf("applyConfigFlags returned error: %v", err)
+	}
+	if want := []string{"Protected:true", "Team=data,ops"}; !reflect.DeepEqual(excludeTags, want) {
+		t.Errorf("excludeTags = %q, want %q", excludeTags, want)
+	}
+	if want := []string{"eu-west-1", "eu-central-1"}; !reflect.DeepEqual(regions, want) {
+		t.Errorf("regions = %q, want %q", regions, want)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// schedule is a recurring window during which instances should be running,
+// defined in the config file, e.g.:
+//
+//	schedules:
+//	  weekday-business-hours:
+//	    days: [mon, tue, wed, thu, fri]
+//	    start: "08:00"
+//	    stop: "18:00"
+//	    timezone: America/New_York
+//
+// A stop time earlier than the start time defines an overnight window, which
+// ends on the day after it starts.
+type schedule struct {
+	Days     []string
+	Start    string
+	Stop     string
+	Timezone string
+}
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Start and stop instances on schedules",
+	Long: `This command starts and stops instances according to the schedules
+	defined under schedules in the config file. Instances select a schedule by
+	name with a tag, Schedule by default.`,
+}
+
+// scheduleApplyCmd represents the schedule apply command
+var scheduleApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Start or stop scheduled instances to match their schedules",
+	Long: `This command compares the current state of every instance with a schedule
+	tag against the state its schedule wants for the current time, and starts
+	or stops the instance when they differ. It is meant to be run periodically,
+	e.g. from cron.
+
+	Examples:
+	# Apply the schedules to all instances
+	ec2ctl schedule apply
+	# Report what would change without starting or stopping instances
+	ec2ctl schedule apply --dry-run
+	`,
+	Run: applySchedules,
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleApplyCmd)
+
+	scheduleApplyCmd.Flags().String("schedule-tag", "Schedule", "Tag key whose value names the schedule of an instance")
+	scheduleApplyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report what would change without starting or stopping the instances")
+}
+
+func applySchedules(cmd *cobra.Command, _ []string) {
+	scheduleTag, err := cmd.Flags().GetString("schedule-tag")
+	if err != nil {
+		fmt.Println("cannot get value of schedule-tag flag:", err)
+		return
+	}
+
+	// Viper lowercases the schedule names, so they are matched
+	// case-insensitively against the tag values
+	var schedules map[string]schedule
+	if err := viper.UnmarshalKey("schedules", &schedules); err != nil {
+		fmt.Println("invalid schedules in the config file:", err)
+		os.Exit(1)
+	}
+	if len(schedules) == 0 {
+		fmt.Println("no schedules are defined in the config file")
+		os.Exit(1)
+	}
+
+	// Only query the instances with a schedule tag
+	filter := make(map[string][]string, len(tags)+1)
+	for k, v := range tags {
+		filter[k] = v
+	}
+	if _, ok := filter[scheduleTag]; !ok {
+		filter[scheduleTag] = []string{"*"}
+	}
+
+	queryCtx, cancelQuery := newContext()
+	accSum := getAccountSummary(queryCtx, regions, filter, aws.InstanceStatus, nil, nil)
+	cancelQuery()
+
+	now := time.Now()
+	failed := false
+	for _, regionSum := range accSum {
+		batches := make(map[string][]string)
+		for _, instance := range regionSum.Instances {
+			name := instance.Tags[scheduleTag]
+			s, ok := schedules[strings.ToLower(name)]
+			if !ok {
+				fmt.Printf("Left instance %s alone: schedule %q is not defined.\n", instance.ID, name)
+				continue
+			}
+			wantRunning, err := s.running(now)
+			if err != nil {
+				fmt.Printf("Left instance %s alone: invalid schedule %q: %v\n", instance.ID, name, err)
+				failed = true
+				continue
+			}
+			switch {
+			case wantRunning && (instance.Status == ec2types.InstanceStateNameStopped || instance.Status == "hibernated"):
+				batches[aws.InstanceStart] = append(batches[aws.InstanceStart], instance.ID)
+			case !wantRunning && instance.Status == ec2types.InstanceStateNameRunning:
+				batches[aws.InstanceStop] = append(batches[aws.InstanceStop], instance.ID)
+			default:
+				fmt.Printf("Left instance %s alone: %s matches schedule %q.\n", instance.ID, instance.Status, name)
+			}
+		}
+
+		for action, instanceIDs := range batches {
+			ctx, cancel := newContext()
+			_, err := transitionRegion(ctx, regionSum.Region, action, instanceIDs)
+			cancel()
+			if err != nil {
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// running reports whether the schedule wants instances to be running at t
+func (s schedule) running(t time.Time) (bool, error) {
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return false, err
+		}
+		t = t.In(loc)
+	}
+	start, err := minuteOfDay(s.Start)
+	if err != nil {
+		return false, err
+	}
+	stop, err := minuteOfDay(s.Stop)
+	if err != nil {
+		return false, err
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	if start <= stop {
+		return s.onDay(t.Weekday()) && now >= start && now < stop, nil
+	}
+	// An overnight window started today, or started yesterday and hasn't ended
+	if now >= start {
+		return s.onDay(t.Weekday()), nil
+	}
+	return now < stop && s.onDay((t.Weekday()+6)%7), nil
+}
+
+// onDay reports whether the schedule's window starts on the given weekday.
+// A schedule without days applies every day.
+func (s schedule) onDay(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	name := strings.ToLower(day.String()[:3])
+	return slices.ContainsFunc(s.Days, func(d string) bool {
+		return strings.ToLower(d) == name
+	})
+}
+
+// minuteOfDay parses a time of day given as HH:MM into minutes after midnight
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: must be HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
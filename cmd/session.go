@@ -0,0 +1,99 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionCmd represents the session command
+var sessionCmd = &cobra.Command{
+	Use:   "session [INSTANCE-ID]",
+	Short: "Start an SSM Session Manager session on an instance",
+	Long: `This command resolves a running instance by ID or by the global filters
+	and starts an SSM Session Manager session on it. If more than one instance
+	matches, you are asked to choose one.
+
+	The session is started with "aws ssm start-session", so the AWS CLI and the
+	Session Manager plugin must be installed.
+
+	Examples:
+	# Start a session on an instance by ID
+	ec2ctl session i-04f95703166d053ed
+	# Start a session on an instance by tag
+	ec2ctl session --tag Name=bastion
+	`,
+	Args: func(_ *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("at most one instance ID can be given")
+		}
+		if len(args) == 0 {
+			// tags is only parsed from the flags after the arguments are checked
+			if len(tagPairs) == 0 && tagFile == "" && nameRegex == "" {
+				return errors.New("an instance ID or a filter such as --tag is required")
+			}
+			return nil
+		}
+		return validateInstanceID(args[0])
+	},
+	Run: startSession,
+}
+
+func init() {
+	rootCmd.AddCommand(sessionCmd)
+}
+
+func startSession(_ *cobra.Command, args []string) {
+	instance, err := resolveInstance(args, "connect to")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	awsPath, err := exec.LookPath("aws")
+	if err != nil {
+		fmt.Println("cannot find the AWS CLI:", err)
+		os.Exit(1)
+	}
+
+	// Hand the resolved credentials to the AWS CLI, so that --profile and
+	// --role-arn apply to the session as well
+	ctx, cancel := newContext()
+	env, err := aws.CredentialsEnv(ctx, creds, instance.Region)
+	cancel()
+	if err != nil {
+		fmt.Println("cannot resolve credentials:", err)
+		os.Exit(1)
+	}
+
+	cmdArgs := []string{"ssm", "start-session", "--target", instance.ID, "--region", instance.Region}
+	logger.Debug("starting session", "instance", instance.ID, "args", cmdArgs)
+	runInteractive(awsPath, cmdArgs, env)
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/spf13/cobra"
+)
+
+// sshCmd represents the ssh command
+var sshCmd = &cobra.Command{
+	Use:   "ssh [INSTANCE-ID] [-- [SSH-OPTIONS...] [COMMAND...]]",
+	Short: "Open an SSH session to an instance",
+	Long: `This command resolves a running instance by ID or by the global filters
+	and runs the local ssh binary against its IP address. If more than one
+	instance matches, you are asked to choose one.
+
+	Arguments after -- that start with a dash are passed to ssh as options,
+	and the first argument that doesn't starts the command to run on the
+	instance.
+
+	Examples:
+	# Connect to an instance by ID
+	ec2ctl ssh i-04f95703166d053ed
+	# Connect to an instance by tag, using its public IP
+	ec2ctl ssh --tag Name=jump-box --public --user ec2-user
+	# Pass extra options to ssh
+	ec2ctl ssh i-04f95703166d053ed -- -L 8080:localhost:80
+	# Run a command on the instance
+	ec2ctl ssh i-04f95703166d053ed -- -t uptime
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		ids := args
+		if cmd.ArgsLenAtDash() >= 0 {
+			ids = args[:cmd.ArgsLenAtDash()]
+		}
+		if len(ids) > 1 {
+			return errors.New("at most one instance ID can be given")
+		}
+		if len(ids) == 0 {
+			// tags is only parsed from the flags after the arguments are checked
+			if len(tagPairs) == 0 && tagFile == "" && nameRegex == "" {
+				return errors.New("an instance ID or a filter such as --tag is required")
+			}
+			return nil
+		}
+		return validateInstanceID(ids[0])
+	},
+	Run: sshInstance,
+}
+
+func init() {
+	rootCmd.AddCommand(sshCmd)
+
+	sshCmd.Flags().Bool("public", false, "Connect to the public IP address instead of the private one")
+	sshCmd.Flags().StringP("user", "l", "", "User to log in as")
+	sshCmd.Flags().StringP("identity", "i", "", "Identity (private key) file to authenticate with")
+}
+
+func sshInstance(cmd *cobra.Command, args []string) {
+	var ids, sshArgs []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		ids, sshArgs = args[:dash], args[dash:]
+	} else {
+		ids = args
+	}
+
+	public, err := cmd.Flags().GetBool("public")
+	if err != nil {
+		fmt.Println("cannot get value of public flag:", err)
+		return
+	}
+	user, err := cmd.Flags().GetString("user")
+	if err != nil {
+		fmt.Println("cannot get value of user flag:", err)
+		return
+	}
+	identity, err := cmd.Flags().GetString("identity")
+	if err != nil {
+		fmt.Println("cannot get value of identity flag:", err)
+		return
+	}
+
+	instance, err := resolveInstance(ids, "connect to")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	ip := instance.IP
+	if public {
+		ip = instance.PublicIP
+	}
+	if ip == "" {
+		fmt.Printf("instance %s has no IP address to connect to\n", instance.ID)
+		os.Exit(1)
+	}
+
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		fmt.Println("cannot find ssh:", err)
+		os.Exit(1)
+	}
+
+	var cmdArgs []string
+	if identity != "" {
+		cmdArgs = append(cmdArgs, "-i", identity)
+	}
+	if user != "" {
+		cmdArgs = append(cmdArgs, "-l", user)
+	}
+	options, command := splitSSHArgs(sshArgs)
+	cmdArgs = append(cmdArgs, options...)
+	cmdArgs = append(cmdArgs, ip)
+	cmdArgs = append(cmdArgs, command...)
+
+	logger.Debug("running ssh", "instance", instance.ID, "args", cmdArgs)
+	runInteractive(sshPath, cmdArgs, nil)
+}
+
+// sshOptionsWithValue are the ssh options that take a value as the next
+// argument, e.g. -L 8080:localhost:80
+const sshOptionsWithValue = "BbcDEeFIiJLlmOopQRSWw"
+
+// splitSSHArgs splits the arguments given after -- into the ssh options,
+// which go before the host, and the command to run on the instance, which
+// goes after it. The command starts at the first argument that isn't an
+// option or the value of one, or after a second --.
+func splitSSHArgs(args []string) ([]string, []string) {
+	var options []string
+	for n := 0; n < len(args); n++ {
+		arg := args[n]
+		if arg == "--" {
+			return options, args[n+1:]
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return options, args[n:]
+		}
+		options = append(options, arg)
+		// The value of an option such as -L is the next argument, unless it
+		// is attached, as in -L8080:localhost:80
+		if len(arg) == 2 && strings.ContainsRune(sshOptionsWithValue, rune(arg[1])) && n+1 < len(args) {
+			n++
+			options = append(options, args[n])
+		}
+	}
+	return options, nil
+}
+
+// resolveInstance returns the single running instance matching the given IDs
+// and the global filters, asking the user to choose one if several match
+func resolveInstance(instanceIDs []string, action string) (aws.Instance, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	accSum := getAccountSummary(ctx, regions, tags, "", instanceIDs, []ec2types.InstanceStateName{ec2types.InstanceStateNameRunning})
+
+	var matches int
+	for _, r := range accSum {
+		matches += len(r.Instances)
+	}
+	if matches == 0 {
+		return aws.Instance{}, errors.New("no running instance matches")
+	}
+	if matches > 1 {
+		accSum = accSum.Pick(action)
+	}
+
+	var picked []aws.Instance
+	for _, r := range accSum {
+		picked = append(picked, r.Instances...)
+	}
+	if len(picked) != 1 {
+		return aws.Instance{}, fmt.Errorf("exactly one instance must be chosen, got %d", len(picked))
+	}
+	return picked[0], nil
+}
+
+// runInteractive runs a command attached to the terminal, with env added to
+// the environment, and exits with its exit code
+func runInteractive(path string, args []string, env []string) {
+	c := exec.Command(path, args...)
+	c.Env = append(os.Environ(), env...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Println("cannot run", path+":", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSSHArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantOptions []string
+		wantCommand []string
+	}{
+		{
+			name: "nothing",
+		},
+		{
+			name:        "options only",
+			args:        []string{"-L", "8080:localhost:80", "-v"},
+			wantOptions: []string{"-L", "8080:localhost:80", "-v"},
+		},
+		{
+			name:        "command only",
+			args:        []string{"uptime"},
+			wantCommand: []string{"uptime"},
+		},
+		{
+			name:        "options and command",
+			args:        []string{"-t", "-o", "StrictHostKeyChecking=no", "sudo", "systemctl", "status", "-l"},
+			wantOptions: []string{"-t", "-o", "StrictHostKeyChecking=no"},
+			wantCommand: []string{"sudo", "systemctl", "status", "-l"},
+		},
+		{
+			name:        "attached value",
+			args:        []string{"-p2222", "uptime"},
+			wantOptions: []string{"-p2222"},
+			wantCommand: []string{"uptime"},
+		},
+		{
+			name:        "command after a second dash",
+			args:        []string{"-v", "--", "-weird-command"},
+			wantOptions: []string{"-v"},
+			wantCommand: []string{"-weird-command"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, command := splitSSHArgs(tt.args)
+			if !reflect.DeepEqual(options, tt.wantOptions) || !reflect.DeepEqual(command, tt.wantCommand) {
+				t.Errorf("splitSSHArgs(%q) = %q, %q, want %q, %q", tt.args, options, command, tt.wantOptions, tt.wantCommand)
+			}
+		})
+	}
+}
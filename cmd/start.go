@@ -23,13 +23,23 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/frgrisk/ec2ctl/adapter/aws"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/spf13/cobra"
 )
 
@@ -41,79 +51,692 @@ var startCmd = &cobra.Command{
 	starts the matched instance(s).
 
 	Examples:
-	# Start all regions
+	# Start the default region
 	ec2ctl start
+	# Start all regions
+	ec2ctl start --all-regions
 	# Start specific regions
 	ec2ctl start --regions us-east-1,ap-southeast-1
 	# Start specific tags
 	ec2ctl start --tag Environment:dev
+	# Start without prompting for confirmation, e.g. in CI
+	ec2ctl start --tag Environment:dev --yes
+	# Refuse to start more than 10 instances
+	ec2ctl start --tag Environment:dev --limit 10
+	# Start the instances with the lowest StartPriority tag first, waiting
+	# for them to be running before starting the next priority
+	ec2ctl start --tag Environment:dev --order StartPriority
 	`,
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		return validateLifecycle(lifecycle)
+	},
 	Run: func(_ *cobra.Command, args []string) {
 		startStop(args, aws.InstanceStart)
 	},
 }
 
+var (
+	wait           bool
+	waitTimeout    time.Duration
+	dryRun         bool
+	interactive    bool
+	limit          int
+	allInstances   bool
+	lifecycle      string
+	instanceIDFile string
+	auditTag       bool
+	startOrder     string
+)
+
+// noLimit is the value of --limit that doesn't cap the number of instances
+const noLimit = -1
+
+// checkLimit aborts the command if more instances matched than allowed by
+// --limit, so that an overly broad filter can't act on the whole fleet
+func checkLimit(count int) {
+	if limit == noLimit || count <= limit {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%d instances matched, which exceeds the limit of %d; aborting.\n", count, limit)
+	os.Exit(1)
+}
+
+// validateLifecycle checks that l is empty or one of the instance lifecycles
+// reported in the Lifecycle column
+func validateLifecycle(l string) error {
+	if l == "" {
+		return nil
+	}
+	known := []string{string(ec2types.InstanceLifecycleOnDemand)}
+	for _, v := range ec2types.InstanceLifecycleType("").Values() {
+		known = append(known, string(v))
+	}
+	if !slices.Contains(known, l) {
+		return fmt.Errorf("invalid lifecycle %q: must be one of %s", l, strings.Join(known, ", "))
+	}
+	return nil
+}
+
+// validateInstanceArgs checks that instances are selected. Arguments that
+// aren't instance IDs are Name tag selectors, resolved when the instances
+// are looked up. It runs before PersistentPreRunE derives the regions from
+// --az and --group, so those flags are checked directly.
 func validateInstanceArgs(args []string) error {
-	if len(args) < 1 && len(regions) == 0 {
-		return errors.New("at least one instance ID is required")
+	if len(args) < 1 && len(regions) == 0 && len(zones) == 0 && group == "" && instanceIDFile == "" {
+		return errors.New("at least one instance ID or name is required")
+	}
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			return errors.New("instance names cannot be empty")
+		}
+	}
+	return nil
+}
+
+// stdinArg is the positional argument that stands for the instance IDs read
+// from stdin, e.g. ec2ctl list --tag x | ec2ctl stop -
+const stdinArg = "-"
+
+// expandStdinArg replaces a "-" argument with the instance IDs read from
+// stdin. It reports whether stdin was read, since it then can't also be used
+// to answer confirmation prompts.
+func expandStdinArg(args []string) ([]string, bool, error) {
+	i := slices.Index(args, stdinArg)
+	if i < 0 {
+		return args, false, nil
+	}
+	ids, err := readInstanceIDs(os.Stdin)
+	if err != nil {
+		return nil, true, fmt.Errorf("cannot read instance IDs from stdin: %w", err)
 	}
-	re := regexp.MustCompile("^i-[a-z|0-9]{8}|[a-z|0-9]{17}")
+	if len(ids) == 0 {
+		return nil, true, errors.New("no instance IDs were read from stdin")
+	}
+	expanded := slices.Delete(slices.Clone(args), i, i+1)
+	return append(expanded, ids...), true, nil
+}
+
+// expandInstanceArgs expands a "-" argument into the instance IDs read from
+// stdin, and adds the instance IDs listed in the --instance-id-file. It
+// reports whether stdin was read.
+func expandInstanceArgs(args []string) ([]string, bool, error) {
+	args, readStdin, err := expandStdinArg(args)
+	if err != nil || instanceIDFile == "" {
+		return args, readStdin, err
+	}
+	f, err := os.Open(instanceIDFile)
+	if err != nil {
+		return nil, readStdin, fmt.Errorf("cannot read instance ID file: %w", err)
+	}
+	defer f.Close()
+	ids, err := readInstanceIDs(f)
+	if err != nil {
+		return nil, readStdin, fmt.Errorf("invalid instance ID file %s: %w", instanceIDFile, err)
+	}
+	if len(ids) == 0 {
+		return nil, readStdin, fmt.Errorf("instance ID file %s lists no instance IDs", instanceIDFile)
+	}
+	for _, id := range ids {
+		if !slices.Contains(args, id) {
+			args = append(args, id)
+		}
+	}
+	return args, readStdin, nil
+}
+
+// readInstanceIDs reads newline-separated instance IDs from r, skipping blank
+// lines and # comments, and validates each of them
+func readInstanceIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := validateInstanceID(line); err != nil {
+			return nil, err
+		}
+		ids = append(ids, line)
+	}
+	return ids, scanner.Err()
+}
+
+// splitInstanceArgs separates positional arguments into instance IDs and
+// Name tag selectors
+func splitInstanceArgs(args []string) (ids []string, names []string) {
 	for _, arg := range args {
-		matched := re.MatchString(arg)
-		if !matched || (len(arg) != 10 && len(arg) != 19) {
-			return fmt.Errorf("%q is not a valid instance id", arg)
+		if validateInstanceID(arg) == nil {
+			ids = append(ids, arg)
+		} else {
+			names = append(names, arg)
+		}
+	}
+	return ids, names
+}
+
+// matchesName reports whether the instance's Name tag matches any of the
+// selectors, which may use the same * and ? wildcards as EC2 filters
+func matchesName(i aws.Instance, names []string) bool {
+	for _, name := range names {
+		if ok, _ := path.Match(name, i.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNameMatches exits if a name selector without wildcards matches more
+// than one instance, since acting on all of them is unlikely to be intended
+func checkNameMatches(accSum aws.AccountSummary, names []string) {
+	ambiguous := false
+	for _, name := range names {
+		if strings.ContainsAny(name, "*?") {
+			continue
 		}
+		var ids []string
+		for _, r := range accSum {
+			for _, i := range r.Instances {
+				if i.Name == name {
+					ids = append(ids, i.ID)
+				}
+			}
+		}
+		if len(ids) > 1 {
+			fmt.Fprintf(os.Stderr, "name %q matches %d instances %v; select them by instance ID instead\n", name, len(ids), ids)
+			ambiguous = true
+		}
+	}
+	if ambiguous {
+		os.Exit(1)
+	}
+}
+
+// reportUnmatchedNames prints the name selectors that matched no instance
+func reportUnmatchedNames(accSum aws.AccountSummary, names []string) {
+	for _, name := range names {
+		found := false
+		for _, r := range accSum {
+			for _, i := range r.Instances {
+				if matchesName(i, []string{name}) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			fmt.Println("no instance named", name, "could be found")
+		}
+	}
+}
+
+var instanceIDRe = regexp.MustCompile("^i-[a-z|0-9]{8}|[a-z|0-9]{17}")
+
+func validateInstanceID(id string) error {
+	matched := instanceIDRe.MatchString(id)
+	if !matched || (len(id) != 10 && len(id) != 19) {
+		return fmt.Errorf("%q is not a valid instance id", id)
 	}
 	return nil
 }
 
 func startStop(instances []string, action string) {
 	var accSum aws.AccountSummary
-	var wg sync.WaitGroup
+
+	instances, readStdin, err := expandInstanceArgs(instances)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	if readStdin && !assumeYes {
+		fmt.Println("Error: stdin can't answer the confirmation prompt once instance IDs are read from it; pass --yes to confirm")
+		os.Exit(1)
+	}
 
 	// Filter instances by region, tags, and current status
-	accSum = getAccountSummary(regions, tags, action, instances)
-	// Show confirmation prompt to user, showing list of matched instances
-	accSum = accSum.Prompt(action)
+	queryCtx, cancelQuery := newContext()
+	accSum = getAccountSummary(queryCtx, regions, tags, action, instances, nil)
+	cancelQuery()
+	accSum = dropInTargetState(accSum, action)
+	checkLimit(accSum.Stats().Total)
+	if interactive {
+		// Let the user pick which of the matched instances to act on
+		accSum = accSum.Pick(action)
+	} else {
+		_, names := splitInstanceArgs(instances)
+		checkNameMatches(accSum, names)
+		if len(accSum) > 0 && !hasFilters(instances) && !allInstances {
+			confirmAll(accSum, action)
+		}
+		// Show confirmation prompt to user, showing list of matched instances
+		accSum = accSum.Prompt(action, tableOptions(os.Stdout), assumeYes)
+	}
+	if action == aws.InstanceStart {
+		accSum = confirmSpotPrices(accSum)
+	}
+
+	// Each round of API calls gets its own --timeout, since waiting between
+	// priority groups can take longer than that
+	ctx, stopInterrupt := notifyInterrupt(context.Background())
+	defer stopInterrupt()
+	var ops opTracker
+
+	var stamp map[string]string
+	if auditTag && !dryRun {
+		stampCtx, cancelStamp := context.WithTimeout(ctx, timeout)
+		stamp, err = auditTags(stampCtx)
+		cancelStamp()
+		if err != nil {
+			fmt.Println("Error: cannot get the caller identity for --audit-tag:", err)
+			os.Exit(1)
+		}
+	}
+
+	// With --order the instances are started one priority group at a time,
+	// waiting for each group to be running before starting the next
+	groups := []priorityGroup{{instances: accSum}}
+	if startOrder != "" && action == aws.InstanceStart {
+		groups = priorityGroups(accSum, startOrder)
+	}
+
+	var failed, batches, waitFailed, waited int
+	for n, g := range groups {
+		if len(groups) > 1 {
+			fmt.Printf("Starting priority group %d of %d (%s=%s)...\n", n+1, len(groups), startOrder, g.label)
+		}
+		groupCtx, cancelGroup := context.WithTimeout(ctx, timeout)
+		groupFailed, groupBatches, waits := applyAction(groupCtx, g.instances, action, stamp, &ops)
+		cancelGroup()
+		failed += groupFailed
+		batches += groupBatches
+		if interrupted(ctx) {
+			ops.report(os.Stderr)
+			os.Exit(exitInterrupted)
+		}
+
+		last := n == len(groups)-1
+		if (wait || !last) && !dryRun && len(waits) > 0 {
+			waitFailed += waitWithProgress(waits)
+			waited += len(waits)
+		}
+		if !last && (groupFailed > 0 || waitFailed > 0) {
+			// Later groups depend on this one, so don't start them
+			fmt.Fprintf(os.Stderr, "Priority group %s=%s did not start; not starting the remaining %d groups.\n", startOrder, g.label, len(groups)-n-1)
+			break
+		}
+	}
 
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d batches of instances failed to %s.\n", failed, batches, action)
+	}
+	if waitFailed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d instances did not reach the target state.\n", waitFailed, waited)
+	}
+	if failed > 0 || waitFailed > 0 {
+		os.Exit(1)
+	}
+}
+
+// applyAction applies the action to the instances of every region in
+// parallel. It returns the number of batches of instances, and of those that
+// failed, along with the instances to wait for.
+func applyAction(ctx context.Context, accSum aws.AccountSummary, action string, stamp map[string]string, ops *opTracker) (int, int, []instanceWait) {
 	// Preprocessing is done to filter and group the instances by the region
 	// The grouping is done such that the maximum number of API calls correlates to the maximum nunber of available regions
 	// Initialised go routine for parallel api calls to increase speed
+	// Failures are collected across the goroutines so the command can exit
+	// non-zero when any instance failed to transition
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed, batches int
+	var waits []instanceWait
 	for _, regionSum := range accSum {
-		wg.Add(1)
-		var instanceIDs []string
-		for _, instance := range regionSum.Instances {
-			instanceIDs = append(instanceIDs, instance.ID)
-		}
-		region := regionSum.Region
-		go func(region string, instanceIDs []string) {
-			defer wg.Done()
-			state, err := aws.StartStopInstance(region, action, instanceIDs)
+		for batchAction, instanceIDs := range actionBatches(regionSum.Instances, action) {
+			batches++
+			wg.Add(1)
+			go func(region string, action string, instanceIDs []string) {
+				defer wg.Done()
+				op := fmt.Sprintf("%s %v in %s", action, instanceIDs, region)
+				ops.start(op)
+				changed, err := transitionRegion(ctx, region, action, instanceIDs)
+				ops.finish(op)
+				if len(stamp) > 0 && len(changed) > 0 {
+					if err := aws.CreateTags(ctx, creds, region, changed, stamp); err != nil {
+						fmt.Printf("Failed to tag instances %q in region %q with the audit tags: %v\n", changed, region, opErrorCause(err))
+					}
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failed++
+				}
+				for _, id := range changed {
+					waits = append(waits, instanceWait{region: region, action: action, id: id})
+				}
+			}(regionSum.Region, batchAction, instanceIDs)
+		}
+	}
+	wg.Wait()
+	return failed, batches, waits
+}
+
+// priorityGroup is a set of instances sharing a value of the --order tag
+type priorityGroup struct {
+	label     string
+	instances aws.AccountSummary
+}
+
+// priorityGroups groups the instances by the integer value of their key tag,
+// in ascending order. Instances without the tag, or with a value that isn't
+// an integer, are started last.
+func priorityGroups(accSum aws.AccountSummary, key string) []priorityGroup {
+	priorities := make(map[string]int)
+	var unordered bool
+	for _, regionSum := range accSum {
+		for _, i := range regionSum.Instances {
+			value, ok := i.Tags[key]
+			if !ok {
+				unordered = true
+				continue
+			}
+			p, err := strconv.Atoi(strings.TrimSpace(value))
 			if err != nil {
-				fmt.Printf("Failed to %s instances %q in region %q: %v\n", action, instanceIDs, region, err)
-				return
+				fmt.Printf("Instance %s has a %s tag of %q, which is not an integer; it will be started last.\n", i.ID, key, value)
+				unordered = true
+				continue
 			}
-			for _, stateChange := range state {
-				if stateChange.PreviousState.Name == stateChange.CurrentState.Name {
-					fmt.Printf(
-						"Instance %s was already in a %s state.\n",
-						*stateChange.InstanceId,
-						stateChange.PreviousState.Name,
-					)
-				} else {
-					fmt.Printf(
-						"Instance %s state changed from %s to %s.\n",
-						*stateChange.InstanceId,
-						stateChange.PreviousState.Name,
-						stateChange.CurrentState.Name,
-					)
-				}
+			priorities[i.ID] = p
+		}
+	}
+
+	var values []int
+	for _, p := range priorities {
+		if !slices.Contains(values, p) {
+			values = append(values, p)
+		}
+	}
+	slices.Sort(values)
+
+	groups := make([]priorityGroup, 0, len(values)+1)
+	for _, v := range values {
+		groups = append(groups, priorityGroup{
+			label: strconv.Itoa(v),
+			instances: filterInstances(accSum, func(i aws.Instance) bool {
+				p, ok := priorities[i.ID]
+				return ok && p == v
+			}),
+		})
+	}
+	if unordered {
+		groups = append(groups, priorityGroup{
+			label: "(none)",
+			instances: filterInstances(accSum, func(i aws.Instance) bool {
+				_, ok := priorities[i.ID]
+				return !ok
+			}),
+		})
+	}
+	return groups
+}
+
+// instanceWait is an instance to wait for after applying an action to it
+type instanceWait struct {
+	region string
+	action string
+	id     string
+}
+
+// waitResult reports that an instance reached its target state, or failed to
+type waitResult struct {
+	instanceWait
+	err error
+}
+
+// waitWithProgress waits for each instance to reach the target state of its
+// action, printing the progress as each instance gets there (e.g. "12/40
+// running"). It returns the number of instances that failed to reach their
+// target state.
+func waitWithProgress(waits []instanceWait) int {
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancelWait()
+	waitCtx, stopInterrupt := notifyInterrupt(waitCtx)
+	defer stopInterrupt()
+
+	fmt.Printf("Waiting for %d instances to reach their target state...\n", len(waits))
+	// Each region is polled once for all of its instances. Instances
+	// hibernated and stopped share the stopped target state, so they are
+	// polled together.
+	byRegion := make(map[string][]instanceWait)
+	for _, w := range waits {
+		byRegion[w.region] = append(byRegion[w.region], w)
+	}
+	results := make(chan waitResult)
+	for region, regionWaits := range byRegion {
+		go func(region string, regionWaits []instanceWait) {
+			pending := make(map[string]instanceWait, len(regionWaits))
+			ids := make([]string, 0, len(regionWaits))
+			for _, w := range regionWaits {
+				pending[w.id] = w
+				ids = append(ids, w.id)
 			}
-		}(region, instanceIDs)
+			err := aws.WaitForInstanceStates(waitCtx, creds, region, regionWaits[0].action, ids, func(id string, err error) {
+				results <- waitResult{instanceWait: pending[id], err: err}
+				delete(pending, id)
+			})
+			// The instances still pending failed along with the poller
+			for _, w := range pending {
+				results <- waitResult{instanceWait: w, err: err}
+			}
+		}(region, regionWaits)
 	}
-	wg.Wait()
+
+	failed := 0
+	done := make(map[ec2types.InstanceStateName]int)
+	for range waits {
+		r := <-results
+		if r.err != nil {
+			failed++
+			fmt.Printf("Failed waiting for instance %s in region %q to %s: %v\n", r.id, r.region, r.action, r.err)
+			continue
+		}
+		state := aws.TargetState(r.action)
+		done[state]++
+		fmt.Printf("Instance %s in region %q is %s (%d/%d %s).\n", r.id, r.region, state, done[state], len(waits), state)
+	}
+	if interrupted(waitCtx) {
+		// The state changes were all issued, only waiting for them was cut short
+		fmt.Fprintf(os.Stderr, "Interrupted while waiting; %d of %d instances had not been confirmed in their target state, but their state changes were issued.\n", failed, len(waits))
+		os.Exit(exitInterrupted)
+	}
+	return failed
+}
+
+// confirmSpotPrices warns about the spot instances whose spot request's max
+// price is below the current spot price, since they are unlikely to start,
+// and asks whether to start them anyway. Declined instances are dropped.
+func confirmSpotPrices(accSum aws.AccountSummary) aws.AccountSummary {
+	unlikely := make(map[string]bool)
+	for _, regionSum := range accSum {
+		ctx, cancel := newContext()
+		warnings, err := aws.SpotPricesAboveMax(ctx, creds, regionSum.Region, regionSum.Instances)
+		cancel()
+		if err != nil {
+			// The check is advisory, so a failure doesn't stop the instances starting
+			fmt.Fprintf(os.Stderr, "Cannot check the spot prices in region %s: %v\n", regionSum.Region, err)
+			continue
+		}
+		for _, w := range warnings {
+			fmt.Printf("Warning: instance %s is unlikely to start: the current spot price of %s in %s is $%.4f, above its spot request's max price of $%.4f.\n",
+				w.Instance.ID, w.Instance.Type, w.Instance.AZ, w.CurrentPrice, w.MaxPrice)
+			unlikely[w.Instance.ID] = true
+		}
+	}
+	if len(unlikely) == 0 || assumeYes {
+		return accSum
+	}
+
+	fmt.Println("\nStart these instances anyway? [y/N]")
+	var s string
+	if _, err := fmt.Scanln(&s); err == nil && strings.EqualFold(s, "y") {
+		return accSum
+	}
+	return filterInstances(accSum, func(i aws.Instance) bool {
+		return !unlikely[i.ID]
+	})
+}
+
+// auditTags returns the tags --audit-tag stamps on the instances it starts or
+// stops, recording who acted on them and when
+func auditTags(ctx context.Context) (map[string]string, error) {
+	caller, err := aws.CallerARN(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"LastActionBy": caller,
+		"LastActionAt": time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// dropInTargetState drops the instances that are already in the state the
+// action would put them in, reporting them instead of calling the API
+func dropInTargetState(accSum aws.AccountSummary, action string) aws.AccountSummary {
+	target := aws.TargetState(action)
+	return filterInstances(accSum, func(i aws.Instance) bool {
+		if i.Status == target || (target == ec2types.InstanceStateNameStopped && i.Status == "hibernated") {
+			fmt.Printf("Instance %s in region %q is already %s.\n", i.ID, i.Region, i.Status)
+			return false
+		}
+		return true
+	})
+}
+
+// actionBatches groups the IDs of the instances by the action to apply to
+// them. Instances without hibernation enabled are stopped instead of
+// hibernated, since AWS rejects hibernating them.
+func actionBatches(instances []aws.Instance, action string) map[string][]string {
+	batches := make(map[string][]string)
+	for _, instance := range instances {
+		batchAction := action
+		if action == aws.InstanceHibernate && !instance.Hibernation {
+			fmt.Printf("Instance %s does not have hibernation enabled and will be stopped instead.\n", instance.ID)
+			batchAction = aws.InstanceStop
+		}
+		batches[batchAction] = append(batches[batchAction], instance.ID)
+	}
+	return batches
+}
+
+// transitionRegion applies the start, stop or hibernate action to instances in
+// a region, reporting each state change. It returns the IDs of the instances
+// the action was applied to, and an error reporting a failure to transition,
+// which has already been printed.
+func transitionRegion(ctx context.Context, region string, action string, instanceIDs []string) ([]string, error) {
+	state, failed, err := aws.StartStopInstance(ctx, creds, region, action, instanceIDs, dryRun)
+	if err != nil {
+		fmt.Printf("Failed to %s instances %q in region %q: %v\n", action, instanceIDs, region, opErrorCause(err))
+		return nil, err
+	}
+
+	// Report the instances that failed on their own, and carry on with the rest
+	var failedErr error
+	if len(failed) > 0 {
+		ids := make([]string, 0, len(failed))
+		for id := range failed {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			fmt.Printf("Failed to %s instance %s in region %q: %v\n", action, id, region, opErrorCause(failed[id]))
+		}
+		instanceIDs = slices.DeleteFunc(slices.Clone(instanceIDs), func(id string) bool {
+			_, ok := failed[id]
+			return ok
+		})
+		failedErr = fmt.Errorf("failed to %s instances %q", action, ids)
+	}
+	if dryRun {
+		for _, stateChange := range state {
+			fmt.Printf("Would %s instance %s.\n", action, *stateChange.InstanceId)
+		}
+		return instanceIDs, failedErr
+	}
+	for _, stateChange := range state {
+		if stateChange.PreviousState.Name == stateChange.CurrentState.Name {
+			fmt.Printf(
+				"Instance %s was already in a %s state.\n",
+				*stateChange.InstanceId,
+				stateChange.PreviousState.Name,
+			)
+		} else {
+			fmt.Printf(
+				"Instance %s state changed from %s to %s (%s).\n",
+				*stateChange.InstanceId,
+				stateChange.PreviousState.Name,
+				stateChange.CurrentState.Name,
+				action,
+			)
+		}
+	}
+	return instanceIDs, failedErr
+}
+
+// hasFilters reports whether any instance IDs or filter flags narrow down the
+// instances a command applies to
+func hasFilters(instances []string) bool {
+	return len(regions) > 0 || hasInstanceFilters(instances)
+}
+
+// hasInstanceFilters reports whether instance IDs or filter flags other than
+// --regions select the instances, since a region on its own matches every
+// instance in it
+func hasInstanceFilters(instances []string) bool {
+	return len(instances) > 0 || len(tags) > 0 || nameRe != nil ||
+		len(exclusions) > 0 || len(filterTypes) > 0 || len(zones) > 0 || len(vpcIDs) > 0 || len(subnetIDs) > 0 ||
+		!createdAfter.IsZero() || !createdBefore.IsZero() || lifecycle != "" ||
+		len(iamProfiles) > 0 || platform != "" || len(tagKeys) > 0 ||
+		len(amiIDs) > 0 || len(launchTemplateIDs) > 0
+}
+
+// confirmAll requires the user to type ALL before acting on every instance in
+// the account, exiting otherwise. Without a terminal to type in, --all-instances
+// must be passed instead.
+func confirmAll(accSum aws.AccountSummary, action string) {
+	count := accSum.Stats().Total
+	if assumeYes {
+		fmt.Fprintf(os.Stderr, "No filters were given, so all %d matching instances in the account would %s; pass --all-instances to confirm.\n", count, action)
+		os.Exit(1)
+	}
+	fmt.Printf("No filters were given, so this will %s all %d matching instances in the account.\nType ALL to continue: ", action, count)
+	var s string
+	_, _ = fmt.Scanln(&s)
+	if s != "ALL" {
+		fmt.Println("Aborted.")
+		os.Exit(0)
+	}
+}
+
+// opErrorCause returns the underlying error of an instance operation that
+// failed, for messages that already name the instances and region
+func opErrorCause(err error) error {
+	var opErr *aws.InstanceOpError
+	if errors.As(err, &opErr) {
+		return opErr.Err
+	}
+	return err
 }
 
 func init() {
 	rootCmd.AddCommand(startCmd)
+
+	startCmd.Flags().StringVar(&group, "group", "", "Name of an instance group defined under groups in the config file")
+	startCmd.Flags().BoolVar(&wait, "wait", false, "Wait until the instances are running before returning")
+	startCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Choose which of the matched instances to start")
+	startCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check permissions and report what would change without starting the instances")
+	startCmd.Flags().BoolVar(&allInstances, "all-instances", false, "Confirm acting on every instance in the account when no filters are given")
+	startCmd.Flags().StringVar(&lifecycle, "lifecycle", "", "Only start instances with this lifecycle (e.g. spot or on-demand)")
+	startCmd.Flags().StringVar(&instanceIDFile, "instance-id-file", "", "File listing the IDs of instances to start, one per line (blank lines and # comments are ignored)")
+	startCmd.Flags().BoolVar(&auditTag, "audit-tag", false, "Tag the started instances with who started them and when (LastActionBy and LastActionAt)")
+	startCmd.Flags().StringVar(&startOrder, "order", "", "Tag key whose integer value orders the start, waiting for each priority to be running before starting the next")
+	startCmd.Flags().IntVar(&limit, "limit", noLimit, "Abort if more than this many instances match (default is no limit)")
+	startCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 10*time.Minute, "Maximum time to wait for the instances when --wait or --order is set")
 }
@@ -23,15 +23,29 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/frgrisk/ec2ctl/adapter/aws"
 	"github.com/frgrisk/ec2ctl/cmd/types"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/spf13/cobra"
 )
 
+var states []ec2types.InstanceStateName
+
+// profiles holds the named profiles to query, set by --profiles or --all-profiles
+var profiles []string
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
@@ -39,56 +53,652 @@ var statusCmd = &cobra.Command{
 	Long: `This command lists all available instances and their statuses.
 
 	Examples:
-	# Query all regions
+	# Query the default region
 	ec2ctl status
+	# Query all regions
+	ec2ctl status --all-regions
 	# Query specific regions
 	ec2ctl status --regions us-east-1,ap-southeast-1
 	# Query specific tags
 	ec2ctl status --tag Environment:dev
+	# Query instances with any of several tag values
+	ec2ctl status --tag Environment:dev --tag Environment:staging
+	# Query by name using wildcards or a regular expression
+	ec2ctl status --tag Name=web-*
+	ec2ctl status --name-regex '^web-[0-9]+$'
+	# Query everything except protected instances
+	ec2ctl status --exclude-tag Protected:true
+	# Query specific states
+	ec2ctl status --state running,stopped
+	# Show the estimated monthly cost of running instances
+	ec2ctl status --with-cost
+	# Refresh every 10 seconds until interrupted
+	ec2ctl status --watch --interval 10s
+	# Sort by launch time, newest first
+	ec2ctl status --sort LaunchTime:desc
+	# Query the instances in a VPC
+	ec2ctl status --vpc vpc-0abc1234
+	# Include recently terminated instances for auditing
+	ec2ctl status --include-terminated
+	# Query every profile in the shared config files
+	ec2ctl status --all-profiles
+	# Print the ID and status of each instance with a template
+	ec2ctl status --template '{{range .}}{{range .Instances}}{{.ID}} {{.Status}}{{"\n"}}{{end}}{{end}}'
+	# Save the status of all instances as CSV
+	ec2ctl status --output csv --output-file instances.csv
 	`,
-	Run: func(_ *cobra.Command, args []string) {
-		// Get account summary based on regions and tags specified
-		accSum := getAccountSummary(regions, tags, aws.InstanceStatus, args)
-
-		if len(accSum) != 0 {
-			switch output {
-			case types.JSON:
-				jsonBytes, err := json.Marshal(accSum)
-				if err != nil {
-					fmt.Println("Error:", err)
-					return
-				}
-				fmt.Println(string(jsonBytes))
-			case types.Table:
-				accSum.Print()
+	PreRunE: func(cmd *cobra.Command, _ []string) error {
+		stateNames, err := cmd.Flags().GetStringSlice("state")
+		if err != nil {
+			return err
+		}
+		states, err = parseStates(stateNames)
+		if err != nil {
+			return err
+		}
+		allProfiles, err := cmd.Flags().GetBool("all-profiles")
+		if err != nil {
+			return err
+		}
+		if allProfiles {
+			if profiles, err = aws.Profiles(); err != nil {
+				return fmt.Errorf("cannot read the profiles: %w", err)
+			}
+			if len(profiles) == 0 {
+				return errors.New("no profiles are defined in the shared config files")
+			}
+		}
+		includeTerminated, err := cmd.Flags().GetBool("include-terminated")
+		if err != nil {
+			return err
+		}
+		if includeTerminated {
+			if len(states) == 0 {
+				states = aws.ActionStates(aws.InstanceStatus)
+			}
+			if !slices.Contains(states, ec2types.InstanceStateNameTerminated) {
+				states = append(states, ec2types.InstanceStateNameTerminated)
+			}
+		}
+		sortSpec, err := cmd.Flags().GetString("sort")
+		if err != nil || sortSpec == "" {
+			return err
+		}
+		return aws.ValidateSort(sortSpec)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		sortSpec, err := cmd.Flags().GetString("sort")
+		if err != nil {
+			fmt.Println("cannot get value of sort flag:", err)
+			return
+		}
+		withCost, err := cmd.Flags().GetBool("with-cost")
+		if err != nil {
+			fmt.Println("cannot get value of with-cost flag:", err)
+			return
+		}
+		var prices *aws.PriceList
+		if withCost {
+			prices = aws.NewPriceList(creds)
+		}
+		watch, err := cmd.Flags().GetBool("watch")
+		if err != nil {
+			fmt.Println("cannot get value of watch flag:", err)
+			return
+		}
+
+		if !watch {
+			queryStatus(context.Background(), args, sortSpec, prices)()
+			return
+		}
+
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			fmt.Println("cannot get value of interval flag:", err)
+			return
+		}
+		if interval <= 0 {
+			fmt.Println("interval must be positive")
+			return
+		}
+
+		// Refresh until interrupted, clearing the screen between refreshes
+		sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			printResult := queryStatus(sigCtx, args, sortSpec, prices)
+			if sigCtx.Err() != nil {
+				return
+			}
+			fmt.Print(clearScreen)
+			fmt.Printf("Every %s: %s\n\n", interval, time.Now().Format(time.RFC1123))
+			printResult()
+
+			select {
+			case <-sigCtx.Done():
+				return
+			case <-ticker.C:
 			}
-		} else {
-			errLabel := "No instances are available for " + aws.InstanceStatus + " command."
-			fmt.Println(errLabel)
 		}
 	},
 }
 
-func getAccountSummary(regions []string, tags map[string]string, action string, instanceIDs []string) (accSum aws.AccountSummary) {
+// clearScreen is the ANSI escape sequence that moves the cursor to the top left
+// and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// queryStatus queries the status of the matching instances, in each of the
+// profiles selected with --profiles or --all-profiles if set, and returns the
+// function that prints it. Each account is queried within the --timeout.
+func queryStatus(parent context.Context, instanceIDs []string, sortSpec string, prices *aws.PriceList) func() {
+	if len(profiles) == 0 && output == types.NDJSON {
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		if err := streamStatus(ctx, instanceIDs, sortSpec, prices); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return func() {}
+	}
+	if len(profiles) == 0 {
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		accSum, err := querySummary(ctx, creds, instanceIDs, sortSpec, prices)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return func() { printStatus(accSum) }
+	}
+
+	summaries := make(aws.ProfileSummaries, 0, len(profiles))
+	for _, profile := range profiles {
+		profileCreds := creds
+		profileCreds.Profile = profile
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		accSum, err := querySummary(ctx, profileCreds, instanceIDs, sortSpec, prices)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to query profile %s: %v\n", profile, err)
+			continue
+		}
+		summaries = append(summaries, aws.ProfileSummary{Profile: profile, Regions: accSum})
+	}
+	return func() { printProfiles(summaries) }
+}
+
+// querySummary gets the account summary for the status command, sorted by the
+// given (already validated) sort spec. If prices is set, the estimated monthly
+// cost of running instances is added.
+func querySummary(ctx context.Context, creds aws.Credentials, instanceIDs []string, sortSpec string, prices *aws.PriceList) (aws.AccountSummary, error) {
+	// Get account summary based on regions and tags specified
+	accSum, err := queryAccount(ctx, creds, regions, tags, aws.InstanceStatus, instanceIDs, states, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if prices != nil {
+		if err := accSum.AddCosts(ctx, prices); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to estimate instance costs:", err)
+		}
+	}
+
+	if sortSpec != "" {
+		// The sort spec was validated before querying
+		_ = accSum.SortBy(sortSpec)
+	}
+	return accSum, nil
+}
+
+// streamStatus writes the matching instances as newline delimited JSON as
+// each region is queried, so that large accounts don't have to be buffered
+// and consumers can start before the slowest region is done. Instances are
+// sorted within each region, but regions are written in the order they
+// finish.
+func streamStatus(ctx context.Context, instanceIDs []string, sortSpec string, prices *aws.PriceList) error {
+	f, closeOutput, err := openOutput()
+	if err != nil {
+		return fmt.Errorf("cannot open output file: %w", err)
+	}
+	var writeErr error
+	_, err = queryAccount(ctx, creds, regions, tags, aws.InstanceStatus, instanceIDs, states, func(regSum aws.RegionSummary) {
+		if writeErr != nil {
+			return
+		}
+		regionSum := aws.AccountSummary{regSum}
+		if prices != nil {
+			if err := regionSum.AddCosts(ctx, prices); err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to estimate instance costs:", err)
+			}
+		}
+		if sortSpec != "" {
+			// The sort spec was validated before querying
+			_ = regionSum.SortBy(sortSpec)
+		}
+		writeErr = regionSum.WriteNDJSON(f)
+	})
+	if closeErr := closeOutput(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// printStatus prints the account summary in the selected output format to
+// stdout, or to the file given by --output-file
+func printStatus(accSum aws.AccountSummary) {
+	if len(accSum) == 0 {
+		errLabel := "No instances are available for " + aws.InstanceStatus + " command."
+		fmt.Println(errLabel)
+		return
+	}
+
+	f, closeOutput, err := openOutput()
+	if err != nil {
+		fmt.Println("cannot open output file:", err)
+		return
+	}
+	defer func() {
+		if err := closeOutput(); err != nil {
+			fmt.Println("cannot write output file:", err)
+		}
+	}()
+
+	switch output {
+	case types.JSON:
+		// Indent the output for humans, but keep it compact when piped
+		var jsonBytes []byte
+		if isTerminal(f) {
+			jsonBytes, err = json.MarshalIndent(accSum, "", "  ")
+		} else {
+			jsonBytes, err = json.Marshal(accSum)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Fprintln(f, string(jsonBytes))
+	case types.CSV:
+		if err := accSum.WriteCSV(f); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	case types.Template:
+		if err := outputTemplate.Execute(f, accSum); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	case types.NDJSON:
+		if err := accSum.WriteNDJSON(f); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	case types.Table, types.Wide:
+		accSum.Print(f, tableOptions(f))
+		accSum.PrintStats(f)
+	}
+}
+
+// printProfiles prints the account summaries of several profiles in the
+// selected output format to stdout, or to the file given by --output-file
+func printProfiles(summaries aws.ProfileSummaries) {
+	f, closeOutput, err := openOutput()
+	if err != nil {
+		fmt.Println("cannot open output file:", err)
+		return
+	}
+	defer func() {
+		if err := closeOutput(); err != nil {
+			fmt.Println("cannot write output file:", err)
+		}
+	}()
+
+	switch output {
+	case types.JSON:
+		var jsonBytes []byte
+		if isTerminal(f) {
+			jsonBytes, err = json.MarshalIndent(summaries, "", "  ")
+		} else {
+			jsonBytes, err = json.Marshal(summaries)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Fprintln(f, string(jsonBytes))
+	case types.CSV:
+		if err := summaries.WriteCSV(f); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	case types.Template:
+		if err := outputTemplate.Execute(f, summaries); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	case types.NDJSON:
+		for _, summary := range summaries {
+			if err := summary.Regions.WriteNDJSON(f); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+		}
+	case types.Table, types.Wide:
+		for _, summary := range summaries {
+			fmt.Fprintf(f, "Profile: %s\n\n", summary.Profile)
+			if len(summary.Regions) == 0 {
+				fmt.Fprintln(f, "No instances are available for "+aws.InstanceStatus+" command.")
+				fmt.Fprintln(f, "")
+				continue
+			}
+			summary.Regions.Print(f, tableOptions(f))
+			summary.Regions.PrintStats(f)
+			fmt.Fprintln(f, "")
+		}
+	}
+}
+
+// getAccountSummary queries the matching instances with the credentials
+// selected by the global flags, exiting if the regions can't be resolved
+func getAccountSummary(ctx context.Context, regions []string, tags map[string][]string, action string, instanceIDs []string, states []ec2types.InstanceStateName) aws.AccountSummary {
+	accSum, err := queryAccount(ctx, creds, regions, tags, action, instanceIDs, states, nil)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	return accSum
+}
+
+// queryAccount queries the matching instances in the regions of the account
+// the credentials belong to. Regions that fail to query are reported and
+// skipped, while failing to resolve the regions is returned as an error. If
+// emit is set, each region with matching instances is passed to it as soon as
+// it has been queried instead of being collected into the returned summary.
+func queryAccount(ctx context.Context, creds aws.Credentials, regions []string, tags map[string][]string, action string, instanceIDs []string, states []ec2types.InstanceStateName, emit func(aws.RegionSummary)) (accSum aws.AccountSummary, err error) {
+	available, err := getRegions(ctx, creds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve regions: %w", err)
+	}
+	// Without --regions, instance IDs are looked up in every region, since
+	// they identify the instances wherever they are. Otherwise only the
+	// default region is queried, unless --all-regions is set.
+	if len(regions) == 0 && len(instanceIDs) == 0 && !allRegions {
+		region, err := aws.DefaultRegion(ctx, creds)
+		if err != nil {
+			return nil, err
+		}
+		if region == "" {
+			return nil, errors.New("no default region is configured: set AWS_REGION, use --regions, or use --all-regions to query every region")
+		}
+		logger.Debug("using the default region", "region", region)
+		regions = []string{region}
+	}
 	if len(regions) == 0 {
-		regions = aws.GetRegions()
+		regions = available
+	} else {
+		regions, err = resolveRegions(regions, available)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Label the regions with the account they belong to, so output from
+	// several accounts can be told apart
+	accountID, err := lookupAccountID(ctx, creds)
+	if err != nil {
+		logger.Warn("cannot get the account ID", "error", err)
 	}
 
-	c := make(chan aws.RegionSummary)
-	for _, r := range regions {
-		go aws.GetDeployedInstances(c, r, tags, action, instanceIDs)
+	// Arguments that aren't instance IDs select instances by their Name tag.
+	// EC2 can only filter on both when every argument is a name, so with a
+	// mix the instances are matched after they are fetched.
+	ids, names := splitInstanceArgs(instanceIDs)
+	if len(names) > 0 {
+		if len(ids) == 0 {
+			if _, ok := tags["Name"]; !ok {
+				nameTags := make(map[string][]string, len(tags)+1)
+				for k, v := range tags {
+					nameTags[k] = v
+				}
+				nameTags["Name"] = names
+				tags = nameTags
+			}
+		}
+		instanceIDs = nil
+	}
+
+	// Filters EC2 can't express server-side are applied to each region as
+	// it arrives
+	keep := clientFilters(ids, names)
+
+	// Show how many regions have been queried while waiting on slow ones,
+	// unless stderr isn't a terminal or is taken by the debug log
+	progress := len(regions) > 1 && !verbose && isTerminal(os.Stderr)
+
+	filter := aws.Filter{
+		Tags:              tags,
+		Action:            action,
+		InstanceIDs:       instanceIDs,
+		States:            states,
+		InstanceTypes:     filterTypes,
+		Zones:             zones,
+		VpcIDs:            vpcIDs,
+		SubnetIDs:         subnetIDs,
+		TagKeys:           tagKeys,
+		AMIIDs:            amiIDs,
+		LaunchTemplateIDs: launchTemplateIDs,
+		Lifecycle:         lifecycle,
 	}
-	var regSum aws.RegionSummary
 
-	for range regions {
-		regSum = <-c
-		if len(regSum.Instances) > 0 {
+	// Failures are sorted into those of regions the account isn't authorized
+	// in and others as each region arrives, so the joined error isn't needed
+	var failed []aws.RegionSummary
+	var unauthorized []string
+	var n int
+	queried, _ := aws.QueryAccount(ctx, creds, regions, filter, func(regSum aws.RegionSummary) {
+		n++
+		if progress {
+			fmt.Fprintf(os.Stderr, "\rQueried %d/%d regions...", n, len(regions))
+		}
+		logger.Debug("queried region", "region", regSum.Region, "instances", len(regSum.Instances), "error", regSum.Err)
+		if aws.IsUnauthorizedError(regSum.Err) {
+			// Expected for regions the account hasn't opted in to
+			logger.Debug("skipping unauthorized region", "region", regSum.Region, "error", regSum.Err)
+			unauthorized = append(unauthorized, regSum.Region)
+			return
+		}
+		if regSum.Err != nil {
+			failed = append(failed, regSum)
+			return
+		}
+		if emit != nil {
+			regSum.AccountID = accountID
+			for _, filtered := range filterInstances(aws.AccountSummary{regSum}, keep...) {
+				emit(filtered)
+			}
+		}
+	})
+	if emit == nil {
+		for _, regSum := range filterInstances(queried, keep...) {
+			regSum.AccountID = accountID
 			accSum = append(accSum, regSum)
 		}
 	}
-	return
+
+	if progress {
+		// Clear the progress line
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+
+	// Report the regions that failed so they aren't mistaken for regions
+	// without any matching instances
+	if len(unauthorized) > 0 {
+		slices.Sort(unauthorized)
+		fmt.Fprintf(os.Stderr, "Skipped %d regions: not authorized %v\n", len(unauthorized), unauthorized)
+	}
+	if len(failed) > 0 {
+		for _, regSum := range failed {
+			fmt.Fprintf(os.Stderr, "Failed to query instances in region %s: %v\n", regSum.Region, regSum.Err)
+		}
+		fmt.Fprintf(os.Stderr, "%d of %d regions failed to query; their instances are not shown.\n", len(failed), len(regions))
+	}
+	return accSum, nil
+}
+
+// clientFilters returns the filters EC2 can't express server-side, such as
+// --name-regex and instances selected by a mix of IDs and names
+func clientFilters(ids []string, names []string) []func(aws.Instance) bool {
+	var keep []func(aws.Instance) bool
+	if len(names) > 0 {
+		keep = append(keep, func(i aws.Instance) bool {
+			return slices.Contains(ids, i.ID) || matchesName(i, names)
+		})
+	}
+	if nameRe != nil {
+		keep = append(keep, func(i aws.Instance) bool {
+			return nameRe.MatchString(i.Name)
+		})
+	}
+	if !createdAfter.IsZero() || !createdBefore.IsZero() {
+		keep = append(keep, func(i aws.Instance) bool {
+			return launchedWithin(i, createdAfter, createdBefore)
+		})
+	}
+	if platform != "" {
+		keep = append(keep, func(i aws.Instance) bool {
+			return i.Platform == platform
+		})
+	}
+	if len(iamProfiles) > 0 {
+		keep = append(keep, func(i aws.Instance) bool {
+			return slices.ContainsFunc(iamProfiles, func(p string) bool {
+				return matchesIAMProfile(i.IAMProfile, p)
+			})
+		})
+	}
+	if len(exclusions) > 0 {
+		keep = append(keep, func(i aws.Instance) bool {
+			return !excluded(i)
+		})
+	}
+	return keep
+}
+
+// accountIDs caches the account ID of each set of credentials, so that it is
+// only looked up once per process rather than on every query, such as each
+// --watch refresh
+var accountIDs = struct {
+	mu  sync.Mutex
+	ids map[aws.Credentials]string
+}{ids: make(map[aws.Credentials]string)}
+
+// lookupAccountID returns the ID of the account the credentials belong to,
+// calling STS the first time only
+func lookupAccountID(ctx context.Context, creds aws.Credentials) (string, error) {
+	accountIDs.mu.Lock()
+	defer accountIDs.mu.Unlock()
+	if id, ok := accountIDs.ids[creds]; ok {
+		return id, nil
+	}
+	id, err := aws.AccountID(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+	accountIDs.ids[creds] = id
+	return id, nil
+}
+
+// excluded reports whether the instance has a tag matching any of the
+// --exclude-tag exclusions
+func excluded(i aws.Instance) bool {
+	for key, values := range exclusions {
+		value, ok := i.Tags[key]
+		if ok && slices.Contains(values, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIAMProfile reports whether the ARN of an instance profile matches
+// profile, which is either the full ARN or the name of the profile
+func matchesIAMProfile(arn string, profile string) bool {
+	if arn == "" {
+		return false
+	}
+	return arn == profile || strings.HasSuffix(arn, "/"+profile)
+}
+
+// launchedWithin reports whether the instance was launched between after and
+// before, either of which may be zero to leave that end of the window open
+func launchedWithin(i aws.Instance, after time.Time, before time.Time) bool {
+	if !after.IsZero() && !i.LaunchTime.After(after) {
+		return false
+	}
+	if !before.IsZero() && !i.LaunchTime.Before(before) {
+		return false
+	}
+	return true
+}
+
+// filterInstances returns the account summary with only the instances for which
+// every keep function returns true, dropping regions that are left without
+// instances
+func filterInstances(accSum aws.AccountSummary, keep ...func(aws.Instance) bool) aws.AccountSummary {
+	filtered := make(aws.AccountSummary, 0, len(accSum))
+	for _, regSum := range accSum {
+		var instances []aws.Instance
+		for _, i := range regSum.Instances {
+			if keepAll(i, keep) {
+				instances = append(instances, i)
+			}
+		}
+		if len(instances) > 0 {
+			regSum.Instances = instances
+			filtered = append(filtered, regSum)
+		}
+	}
+	return filtered
+}
+
+// keepAll reports whether every keep function returns true for the instance
+func keepAll(i aws.Instance, keep []func(aws.Instance) bool) bool {
+	for _, k := range keep {
+		if !k(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseStates converts state names to instance states, returning an error for
+// any name that isn't a valid instance state
+func parseStates(names []string) ([]ec2types.InstanceStateName, error) {
+	states := make([]ec2types.InstanceStateName, 0, len(names))
+	for _, name := range names {
+		state := ec2types.InstanceStateName(strings.ToLower(name))
+		if !slices.Contains(state.Values(), state) {
+			return nil, fmt.Errorf("%q is not a valid instance state (valid states: %v)", name, state.Values())
+		}
+		states = append(states, state)
+	}
+	return states, nil
 }
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVar(&group, "group", "", "name of an instance group defined under groups in the config file")
+	statusCmd.Flags().Bool("with-cost", false, "show the estimated monthly on-demand cost of running instances")
+	statusCmd.Flags().Bool("watch", false, "refresh the status until interrupted")
+	statusCmd.Flags().Duration("interval", 5*time.Second, "time between refreshes when --watch is set")
+	statusCmd.Flags().String("sort", "", "field to sort instances by, optionally suffixed with :desc (default is Environment then Name)")
+	statusCmd.Flags().StringSliceVar(&profiles, "profiles", []string{}, "comma-separated list of named profiles to query, grouping the output by profile")
+	statusCmd.Flags().Bool("all-profiles", false, "query every profile defined in the shared config and credentials files")
+	statusCmd.Flags().Bool("include-terminated", false, "also show terminated instances, which EC2 lists for about an hour after termination")
+	statusCmd.Flags().StringSlice("state", []string{}, "comma-separated list of instance states to show (default is all non-terminated states)")
 }
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+)
+
+func TestClientFiltersNameGlob(t *testing.T) {
+	keep := clientFilters(nil, []string{"web-*"})
+	tests := map[string]bool{
+		"web-1":     true,
+		"web-":      true,
+		"api-web-1": false,
+		"web":       false,
+	}
+	for name, want := range tests {
+		if got := keepAll(aws.Instance{Name: name}, keep); got != want {
+			t.Errorf("glob web-* matching %q = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestClientFiltersNameRegex(t *testing.T) {
+	saved := nameRe
+	t.Cleanup(func() { nameRe = saved })
+	nameRe = regexp.MustCompile(`^(web|api)-[0-9]+$`)
+
+	keep := clientFilters(nil, nil)
+	tests := map[string]bool{
+		"web-1":   true,
+		"api-12":  true,
+		"web-a":   false,
+		"db-1":    false,
+		"x-web-1": false,
+	}
+	for name, want := range tests {
+		if got := keepAll(aws.Instance{Name: name}, keep); got != want {
+			t.Errorf("regex matching %q = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -23,6 +23,9 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/frgrisk/ec2ctl/adapter/aws"
 
 	"github.com/spf13/cobra"
@@ -36,18 +39,62 @@ var stopCmd = &cobra.Command{
 	stop the matched instance(s).
 
 	Examples:
-	# Stop all regions
+	# Stop the default region
 	ec2ctl stop
+	# Stop all regions
+	ec2ctl stop --all-regions
 	# Stop specific regions
 	ec2ctl stop --regions us-east-1,ap-southeast-1
 	# Stop specific tags
 	ec2ctl stop --tag Environment:dev
+	# Stop an instance by its Name tag
+	ec2ctl stop web-server-1
+	# Stop the instances whose IDs are read from stdin, one per line
+	ec2ctl status --tag Team:data --template '{{range .}}{{range .Instances}}{{.ID}}{{println}}{{end}}{{end}}' | ec2ctl stop --yes -
+	# Refuse to stop anything if any production instance matches
+	ec2ctl stop --tag Environment:prod --limit 0
+	# Stop all instances of a type, regardless of their tags
+	ec2ctl stop --filter-type m5.24xlarge
+	# Stop everything in an availability zone
+	ec2ctl stop --az us-east-1a
+	# Stop only the spot instances left over from a batch job
+	ec2ctl stop --tag Job:batch --lifecycle spot
+	# Stop every instance using a compromised instance profile
+	ec2ctl stop --all-regions --iam-profile web-server-role
+	# Hibernate instances that have hibernation enabled
+	ec2ctl stop --hibernate
+	# Stop everything in a region except protected instances
+	ec2ctl stop --regions us-east-1 --exclude-tag Protected:true
 	`,
-	Run: func(_ *cobra.Command, args []string) {
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		return validateLifecycle(lifecycle)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		hibernate, err := cmd.Flags().GetBool("hibernate")
+		if err != nil {
+			fmt.Println("cannot get value of hibernate flag:", err)
+			return
+		}
+		if hibernate {
+			startStop(args, aws.InstanceHibernate)
+			return
+		}
 		startStop(args, aws.InstanceStop)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(stopCmd)
+
+	stopCmd.Flags().StringVar(&group, "group", "", "Name of an instance group defined under groups in the config file")
+	stopCmd.Flags().Bool("hibernate", false, "Hibernate the instances instead of stopping them (instances without hibernation enabled are stopped)")
+	stopCmd.Flags().BoolVar(&wait, "wait", false, "Wait until the instances are stopped before returning")
+	stopCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Choose which of the matched instances to stop")
+	stopCmd.Flags().StringVar(&instanceIDFile, "instance-id-file", "", "File listing the IDs of instances to stop, one per line (blank lines and # comments are ignored)")
+	stopCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check permissions and report what would change without stopping the instances")
+	stopCmd.Flags().BoolVar(&allInstances, "all-instances", false, "Confirm acting on every instance in the account when no filters are given")
+	stopCmd.Flags().StringVar(&lifecycle, "lifecycle", "", "Only stop instances with this lifecycle (e.g. spot or on-demand)")
+	stopCmd.Flags().BoolVar(&auditTag, "audit-tag", false, "Tag the stopped instances with who stopped them and when (LastActionBy and LastActionAt)")
+	stopCmd.Flags().IntVar(&limit, "limit", noLimit, "Abort if more than this many instances match (default is no limit)")
+	stopCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 10*time.Minute, "Maximum time to wait for the instances when --wait is set")
 }
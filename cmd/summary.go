@@ -0,0 +1,108 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+	"github.com/frgrisk/ec2ctl/cmd/types"
+
+	"github.com/spf13/cobra"
+)
+
+// summaryCmd represents the summary command
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Count the matching instances by type, zone, environment, lifecycle or state",
+	Long: `This command counts the matching instances by the value of a field, with how
+	many of each are running, instead of listing every instance.
+
+	Examples:
+	# Count the instances of each type in the default region
+	ec2ctl summary --by type
+	# Count the instances in each state across all regions
+	ec2ctl summary --by state --all-regions
+	`,
+	PreRunE: func(cmd *cobra.Command, _ []string) error {
+		by, err := cmd.Flags().GetString("by")
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(aws.GroupDimensions(), by) {
+			return fmt.Errorf("invalid value %q for --by: must be one of %s", by, strings.Join(aws.GroupDimensions(), ", "))
+		}
+		return nil
+	},
+	Run: summarizeInstances,
+}
+
+func init() {
+	rootCmd.AddCommand(summaryCmd)
+
+	summaryCmd.Flags().String("by", "type", "Field to count the instances by (az, environment, lifecycle, state, type)")
+}
+
+func summarizeInstances(cmd *cobra.Command, args []string) {
+	by, err := cmd.Flags().GetString("by")
+	if err != nil {
+		fmt.Println("cannot get value of by flag:", err)
+		return
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	// Get account summary based on regions and tags specified
+	accSum := getAccountSummary(ctx, regions, tags, aws.InstanceStatus, args, nil)
+	counts, err := accSum.GroupBy(by)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	f, closeOutput, err := openOutput()
+	if err != nil {
+		fmt.Println("cannot open output file:", err)
+		return
+	}
+	defer func() {
+		if err := closeOutput(); err != nil {
+			fmt.Println("cannot write output file:", err)
+		}
+	}()
+
+	if output == types.JSON {
+		jsonBytes, err := json.Marshal(counts)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Fprintln(f, string(jsonBytes))
+		return
+	}
+	aws.WriteGroupCounts(f, by, counts, tableOptions(f))
+}
@@ -0,0 +1,167 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	"github.com/spf13/cobra"
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Add or remove instance tags",
+	Long:  `This command adds or removes tags on one or more instances.`,
+}
+
+// tagAddCmd represents the tag add command
+var tagAddCmd = &cobra.Command{
+	Use:   "add INSTANCE-ID [INSTANCE-ID...]",
+	Short: "Add tags to one or more instances",
+	Long: `This command adds tags to the specified instance(s). Tags that already
+	exist on an instance are overwritten with the new value.`,
+	Args: func(_ *cobra.Command, args []string) error {
+		return validateInstanceArgs(args)
+	},
+	Example: "ec2ctl tag add --tag Owner:jane,Team:data i-04f95703166d053ed",
+	Run:     addTags,
+}
+
+// tagRemoveCmd represents the tag remove command
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove INSTANCE-ID [INSTANCE-ID...]",
+	Short: "Remove tags from one or more instances",
+	Long: `This command removes tags from the specified instance(s), regardless of
+	their values. Keys that are not present on an instance are ignored.`,
+	Args: func(_ *cobra.Command, args []string) error {
+		return validateInstanceArgs(args)
+	},
+	Example: "ec2ctl tag remove --key Owner,Team i-04f95703166d053ed",
+	Run:     removeTags,
+	Aliases: []string{"rm", "delete"},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+
+	// The local --tag flag shadows the persistent filter flag, since the
+	// instances are selected by ID or name.
+	tagAddCmd.Flags().StringSlice("tag", []string{}, "tags to add - specified as key=value or key:value pairs, like the --tag filter of other commands (e.g. Owner=jane,Team:data)")
+	_ = tagAddCmd.MarkFlagRequired("tag")
+	tagRemoveCmd.Flags().StringSlice("key", []string{}, "comma-separated list of tag keys to remove")
+	_ = tagRemoveCmd.MarkFlagRequired("key")
+}
+
+func addTags(cmd *cobra.Command, instances []string) {
+	pairs, err := cmd.Flags().GetStringSlice("tag")
+	if err != nil {
+		fmt.Println("cannot get value of tag flag:", err)
+		return
+	}
+	parsed, err := parseTagPairs(pairs)
+	if err != nil {
+		fmt.Println("error parsing tag flag:", err)
+		return
+	}
+	// An instance can only hold one value for each key
+	newTags := make(map[string]string, len(parsed))
+	for key, values := range parsed {
+		if len(values) > 1 {
+			fmt.Printf("error parsing tag flag: tag %s is given more than one value %v\n", key, values)
+			return
+		}
+		newTags[key] = values[0]
+	}
+
+	for region, ids := range instancesByRegion(instances) {
+		ctx, cancel := newContext()
+		err := aws.CreateTags(ctx, creds, region, ids, newTags)
+		cancel()
+		if err != nil {
+			fmt.Printf("%s: error tagging instances %v: %s\n", region, ids, err)
+		} else {
+			fmt.Printf("%s: successfully tagged the following instances %v\n", region, ids)
+		}
+	}
+}
+
+func removeTags(cmd *cobra.Command, instances []string) {
+	keys, err := cmd.Flags().GetStringSlice("key")
+	if err != nil {
+		fmt.Println("error parsing key flag:", err)
+		return
+	}
+
+	for region, ids := range instancesByRegion(instances) {
+		ctx, cancel := newContext()
+		err := aws.DeleteTags(ctx, creds, region, ids, keys)
+		cancel()
+		if err != nil {
+			fmt.Printf("%s: error removing tags from instances %v: %s\n", region, ids, err)
+		} else {
+			fmt.Printf("%s: successfully removed tags from the following instances %v\n", region, ids)
+		}
+	}
+}
+
+// instancesByRegion looks up the given instances and groups their IDs by
+// region. Instances that cannot be found are reported and left out.
+func instancesByRegion(instances []string) map[string][]string {
+	if !hasInstanceFilters(instances) {
+		fmt.Println("Error: give instance IDs or names, or a filter such as --name-regex; --regions alone would change the tags of every instance in the region")
+		os.Exit(1)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	// Get account summary based on regions and tags specified
+	accSum := getAccountSummary(ctx, regions, tags, "", instances, nil)
+
+	ids, names := splitInstanceArgs(instances)
+	checkNameMatches(accSum, names)
+
+	found := make(map[string]bool, len(instances))
+	instanceRegionMap := make(map[string][]string)
+	for _, r := range accSum {
+		for _, i := range r.Instances {
+			found[i.ID] = true
+			instanceRegionMap[i.Region] = append(instanceRegionMap[i.Region], i.ID)
+		}
+	}
+
+	for _, i := range ids {
+		if !found[i] {
+			fmt.Println("instance", i, "could not be found")
+		}
+	}
+	reportUnmatchedNames(accSum, names)
+
+	return instanceRegionMap
+}
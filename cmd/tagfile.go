@@ -0,0 +1,62 @@
+/*
+Copyright © 2021 FRG
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tagFile is the path of the file given with --tag-file
+var tagFile string
+
+// applyTagFile loads tag filters from a YAML or JSON file mapping tag keys to
+// values and merges them into the tags filter, e.g.:
+//
+//	Environment: dev
+//	Team: data
+//
+// Tags given on the command line take precedence over the file's tags with
+// the same key.
+func applyTagFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read tag file: %w", err)
+	}
+	// JSON is a subset of YAML, so one parser handles both formats
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("invalid tag file %s: %w", path, err)
+	}
+	for k, v := range raw {
+		value, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("invalid tag file %s: the value of tag %q must be a string", path, k)
+		}
+		if _, set := tags[k]; !set {
+			tags[k] = []string{value}
+		}
+	}
+	return nil
+}
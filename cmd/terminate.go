@@ -24,11 +24,16 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/frgrisk/ec2ctl/adapter/aws"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/spf13/cobra"
 )
 
@@ -50,23 +55,56 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	terminateCmd.Flags().BoolP("force", "f", false, "Force terminate the instance (do not prompt for confirmation)")
+	terminateCmd.Flags().Bool("disable-protection", false, "Disable the termination protection of protected instances before terminating them")
+	terminateCmd.Flags().Bool("cancel-spot-request", true, "Cancel the persistent spot requests of the terminated instances so they aren't relaunched")
+	terminateCmd.Flags().StringVar(&instanceIDFile, "instance-id-file", "", "File listing the IDs of instances to terminate, one per line (blank lines and # comments are ignored)")
+	terminateCmd.Flags().IntVar(&limit, "limit", noLimit, "Abort if more than this many instances match (default is no limit)")
+	terminateCmd.Flags().String("journal", "", "Record the outcome of terminating each instance in this JSON file as each region completes")
+	terminateCmd.Flags().String("resume", "", "Resume from a journal, skipping the instances it records as terminated and recording progress in it")
+	terminateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Check permissions and report what would be terminated without terminating the instances")
 }
 
 func terminateInstance(cmd *cobra.Command, instances []string) {
+	instances, readStdin, err := expandInstanceArgs(instances)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	journal, err := openJournal(cmd)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
 	// Get account summary based on regions and tags specified
-	accSum := getAccountSummary(regions, tags, "", instances)
+	queryCtx, cancelQuery := newContext()
+	accSum := getAccountSummary(queryCtx, regions, tags, "", instances, nil)
+	cancelQuery()
 
 	instanceMap := make(map[string]*aws.Instance, 0)
 	instanceRegionMap := make(map[string][]string, 0)
 
-	for _, i := range instances {
+	// Instances selected by name are only known once they are found
+	ids, names := splitInstanceArgs(instances)
+	skipped := 0
+	for _, i := range ids {
+		// Instances a previous run terminated are no longer found
+		if journal != nil && journal.terminated(i) {
+			skipped++
+			continue
+		}
 		instanceMap[i] = nil
 	}
+	if skipped > 0 {
+		fmt.Printf("Skipping %d instances the journal records as terminated.\n", skipped)
+	}
+	checkNameMatches(accSum, names)
+	reportUnmatchedNames(accSum, names)
 
 	for _, r := range accSum {
 		for n, i := range r.Instances {
 			_, ok := instanceMap[i.ID]
-			if ok {
+			if ok || matchesName(i, names) {
 				instanceMap[i.ID] = &r.Instances[n]
 				if _, ok := instanceRegionMap[i.Region]; !ok {
 					instanceRegionMap[i.Region] = []string{}
@@ -76,13 +114,50 @@ func terminateInstance(cmd *cobra.Command, instances []string) {
 		}
 	}
 
+	matched := 0
+	for _, ids := range instanceRegionMap {
+		matched += len(ids)
+	}
+	checkLimit(matched)
+
 	force, err := cmd.Flags().GetBool("force")
 	if err != nil {
 		fmt.Println("cannot get value of force flag:", err)
 		return
 	}
+	if readStdin && !force && !dryRun && !assumeYes {
+		fmt.Println("Error: stdin can't answer the confirmation prompts once instance IDs are read from it; pass --yes to confirm")
+		os.Exit(1)
+	}
+	cancelSpot, err := cmd.Flags().GetBool("cancel-spot-request")
+	if err != nil {
+		fmt.Println("cannot get value of cancel-spot-request flag:", err)
+		return
+	}
+	disableProtection, err := cmd.Flags().GetBool("disable-protection")
+	if err != nil {
+		fmt.Println("cannot get value of disable-protection flag:", err)
+		return
+	}
+	// Show everything that will be terminated before any prompt, so the whole
+	// blast radius is visible at once
+	if matched > 0 {
+		preview := filterInstances(accSum, func(i aws.Instance) bool {
+			return instanceMap[i.ID] != nil
+		})
+		if dryRun {
+			fmt.Printf("Dry run: the following %d instances would be terminated:\n\n", matched)
+		} else {
+			fmt.Printf("The following %d instances will be terminated:\n\n", matched)
+		}
+		preview.Print(os.Stdout, tableOptions(os.Stdout))
+	}
+
+	// Confirm each region first, so that the prompts aren't interleaved with
+	// the output of the terminations running in parallel
+	approved := make(map[string][]string, len(instanceRegionMap))
 	for k, v := range instanceRegionMap {
-		if !force {
+		if !force && !dryRun && !assumeYes {
 			fmt.Printf(`Are you sure you want to terminate instances %v in region %s?
 	Only 'yes' will be accepted to approve
 
@@ -94,12 +169,47 @@ func terminateInstance(cmd *cobra.Command, instances []string) {
 				continue
 			}
 		}
-		err := aws.TerminateInstances(k, v)
-		if err != nil {
-			fmt.Printf("%s: error terminating instances %v: %s\n", k, v, err)
-		} else {
-			fmt.Printf("%s: successfully terminated the following instances %v\n", k, v)
-		}
+		approved[k] = v
+	}
+
+	ctx, stopInterrupt := notifyInterrupt(context.Background())
+	defer stopInterrupt()
+	var ops opTracker
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for k, v := range approved {
+		wg.Add(1)
+		go func(region string, ids []string) {
+			defer wg.Done()
+			op := fmt.Sprintf("terminate %v in %s", ids, region)
+			ops.start(op)
+			skipped, err := terminateRegion(ctx, region, ids, instanceMap, cancelSpot, disableProtection)
+			ops.finish(op)
+			if journal != nil && !dryRun {
+				attempted := slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+					return slices.Contains(skipped, id)
+				})
+				jErr := journal.record(region, attempted, err)
+				if jErr == nil && len(skipped) > 0 {
+					jErr = journal.recordOutcome(region, skipped, outcomeProtected)
+				}
+				if jErr != nil {
+					fmt.Fprintf(os.Stderr, "%s: cannot write the journal: %v\n", region, jErr)
+				}
+			}
+			if err != nil {
+				mu.Lock()
+				failed = append(failed, region)
+				mu.Unlock()
+			}
+		}(k, v)
+	}
+	wg.Wait()
+	if interrupted(ctx) {
+		ops.report(os.Stderr)
+		os.Exit(exitInterrupted)
 	}
 
 	for k, v := range instanceMap {
@@ -107,4 +217,126 @@ func terminateInstance(cmd *cobra.Command, instances []string) {
 			fmt.Println("instance", k, "could not be found")
 		}
 	}
+
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		fmt.Fprintf(os.Stderr, "%d of %d regions failed to terminate instances: %v\n", len(failed), len(approved), failed)
+		os.Exit(1)
+	}
+}
+
+// openJournal loads the journal given by --resume, or starts the one given by
+// --journal. Without either, it returns nil and no journal is kept.
+func openJournal(cmd *cobra.Command) (*terminateJournal, error) {
+	path, err := cmd.Flags().GetString("journal")
+	if err != nil {
+		return nil, err
+	}
+	resume, err := cmd.Flags().GetString("resume")
+	if err != nil {
+		return nil, err
+	}
+	if resume == "" {
+		if path == "" {
+			return nil, nil
+		}
+		return &terminateJournal{path: path, entries: make(map[string]journalEntry)}, nil
+	}
+	journal, err := loadJournal(resume)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read journal %s: %w", resume, err)
+	}
+	// Progress is recorded in the journal being resumed, unless another is given
+	if path != "" {
+		journal.path = path
+	}
+	return journal, nil
+}
+
+// terminateRegion terminates the instances in a region, first dealing with
+// their termination protection and persistent spot requests. Results are
+// printed as they happen. It returns the instances that were skipped because
+// of their termination protection, and an error reporting a failure that has
+// already been printed.
+func terminateRegion(parent context.Context, region string, ids []string, instanceMap map[string]*aws.Instance, cancelSpot bool, disableProtection bool) ([]string, error) {
+	// Check for termination protection before anything is changed, so that
+	// protection is only ever disabled when explicitly asked for
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	protected, err := aws.ProtectedInstances(ctx, creds, region, ids)
+	cancel()
+	if err != nil {
+		fmt.Printf("%s: error checking termination protection of instances %v: %s\n", region, ids, err)
+		return nil, err
+	}
+	var skipped []string
+	if len(protected) > 0 {
+		if disableProtection {
+			ctx, cancel := context.WithTimeout(parent, timeout)
+			err := aws.DisableTerminationProtection(ctx, creds, region, protected, dryRun)
+			cancel()
+			if err != nil {
+				fmt.Printf("%s: error disabling termination protection of instances %v: %s\n", region, protected, err)
+				return nil, err
+			} else if dryRun {
+				fmt.Printf("%s: would disable termination protection of the following instances %v\n", region, protected)
+			} else {
+				fmt.Printf("%s: disabled termination protection of the following instances %v\n", region, protected)
+			}
+		} else {
+			for _, id := range protected {
+				fmt.Printf("%s: instance %s has termination protection enabled and will not be terminated (use --disable-protection to override)\n", region, id)
+			}
+			skipped = protected
+			ids = slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+				return slices.Contains(protected, id)
+			})
+			if len(ids) == 0 {
+				return skipped, nil
+			}
+		}
+	}
+	// Cancel persistent spot requests first, otherwise they launch
+	// replacements for the terminated instances
+	if cancelSpot {
+		if requestIDs := persistentSpotRequests(instanceMap, ids); len(requestIDs) > 0 {
+			ctx, cancel := context.WithTimeout(parent, timeout)
+			err := aws.CancelSpotRequests(ctx, creds, region, requestIDs, dryRun)
+			cancel()
+			if err != nil {
+				fmt.Printf("%s: error cancelling spot requests %v: %s\n", region, requestIDs, err)
+				return skipped, err
+			} else if dryRun {
+				fmt.Printf("%s: would cancel the following spot requests %v\n", region, requestIDs)
+			} else {
+				fmt.Printf("%s: successfully cancelled the following spot requests %v\n", region, requestIDs)
+			}
+		}
+	}
+	ctx, cancel = context.WithTimeout(parent, timeout)
+	err = aws.TerminateInstances(ctx, creds, region, ids, dryRun)
+	cancel()
+	if aws.IsTerminationProtectedError(err) {
+		fmt.Printf("%s: instances %v could not be terminated because termination protection is enabled (use --disable-protection to override): %s\n", region, ids, opErrorCause(err))
+	} else if err != nil {
+		fmt.Printf("%s: error terminating instances %v: %s\n", region, ids, opErrorCause(err))
+	} else if dryRun {
+		fmt.Printf("%s: would terminate the following instances %v\n", region, ids)
+	} else {
+		fmt.Printf("%s: successfully terminated the following instances %v\n", region, ids)
+	}
+	return skipped, err
+}
+
+// persistentSpotRequests returns the IDs of the persistent spot requests that
+// launched the given instances. One-time requests are closed once their
+// instance is terminated, so they don't need to be cancelled.
+func persistentSpotRequests(instanceMap map[string]*aws.Instance, ids []string) []string {
+	var requestIDs []string
+	for _, id := range ids {
+		i := instanceMap[id]
+		if i != nil && i.SpotRequestID != "" && i.SpotInstanceType == ec2types.SpotInstanceTypePersistent {
+			requestIDs = append(requestIDs, i.SpotRequestID)
+		}
+	}
+	return requestIDs
 }
@@ -12,6 +12,10 @@ type Output int
 const (
 	Table Output = iota
 	JSON
+	CSV
+	Wide
+	Template
+	NDJSON
 )
 
 // Set converts a string to the output type
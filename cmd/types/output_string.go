@@ -10,11 +10,15 @@ func _() {
 	var x [1]struct{}
 	_ = x[Table-0]
 	_ = x[JSON-1]
+	_ = x[CSV-2]
+	_ = x[Wide-3]
+	_ = x[Template-4]
+	_ = x[NDJSON-5]
 }
 
-const _Output_name = "TableJSON"
+const _Output_name = "TableJSONCSVWideTemplateNDJSON"
 
-var _Output_index = [...]uint8{0, 5, 9}
+var _Output_index = [...]uint8{0, 5, 9, 12, 16, 24, 30}
 
 func (i Output) String() string {
 	if i < 0 || i >= Output(len(_Output_index)-1) {